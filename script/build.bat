@@ -40,6 +40,18 @@ go mod tidy
 go build -o ../../dist/nctl.exe main.go
 cd ../../
 
+@rem StorageCTL
+cd ./src/StorageCTL
+go mod tidy
+go build -o ../../dist/sctl.exe main.go
+cd ../../
+
+@rem SchedulerCTL
+cd ./src/SchedulerCTL
+go mod tidy
+go build -o ../../dist/schctl.exe main.go
+cd ../../
+
 
 @rem ----------------------------------------
 
@@ -75,4 +87,16 @@ cd ../../
 cd ./src/NacosCTL
 go mod tidy
 go build -o ../../dist/nctl main.go
+cd ../../
+
+@rem StorageCTL
+cd ./src/StorageCTL
+go mod tidy
+go build -o ../../dist/sctl main.go
+cd ../../
+
+@rem SchedulerCTL
+cd ./src/SchedulerCTL
+go mod tidy
+go build -o ../../dist/schctl main.go
 cd ../../
\ No newline at end of file
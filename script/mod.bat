@@ -30,4 +30,14 @@ cd ../../
 @rem NacosCTL
 cd ./src/NacosCTL
 go mod tidy
+cd ../../
+
+@rem StorageCTL
+cd ./src/StorageCTL
+go mod tidy
+cd ../../
+
+@rem SchedulerCTL
+cd ./src/SchedulerCTL
+go mod tidy
 cd ../../
\ No newline at end of file
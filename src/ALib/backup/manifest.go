@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/longyuan/lib.v3/ctl"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ManifestName 清单文件名, 位于归档根目录
+const ManifestName = "manifest.json"
+
+// Manifest 备份清单; 记录备份来源、时间、对象数量和每个文件的 SHA-256
+type Manifest struct {
+	// Source 来源工具 (nacos, gitlab, kubernetes)
+	Source string `json:"source"`
+	// Name 备份对象 (Nacos 地址、Gitlab 地址、集群名称)
+	Name string `json:"name"`
+	// Version 程序版本号
+	Version string    `json:"version"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	// Counts 对象类型 -> 数量 (如 Deployment: 10)
+	Counts map[string]int `json:"counts"`
+	Files  []File         `json:"files"`
+
+	lock sync.Mutex
+}
+
+// File 清单中的文件
+type File struct {
+	// Name 归档内路径 (使用 / 分隔)
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// NewManifest 创建备份清单, 开始时间为当前时间; source 来源工具, name 备份对象
+func NewManifest(source, name string) *Manifest {
+	return &Manifest{Source: source, Name: name, Version: ctl.Version, Start: time.Now(), Counts: map[string]int{}}
+}
+
+// Count 累加对象数量
+func (manifest *Manifest) Count(kind string, count int) {
+	manifest.lock.Lock()
+	defer manifest.lock.Unlock()
+	manifest.Counts[kind] += count
+}
+
+// Write 计算目录下所有文件的 SHA-256 并写入 manifest.json; 结束时间为当前时间
+func (manifest *Manifest) Write(directory string) error {
+	manifest.lock.Lock()
+	defer manifest.lock.Unlock()
+	manifest.Files = nil
+	err := filepath.Walk(directory, func(filePath string, fi os.FileInfo, errBack error) error {
+		if errBack != nil {
+			return errBack
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		name, err := filepath.Rel(directory, filePath)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if name == ManifestName {
+			return nil
+		}
+		sum, err := fileSum(filePath)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, File{Name: name, Size: fi.Size(), SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Name < manifest.Files[j].Name
+	})
+	manifest.End = time.Now()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(directory, ManifestName), data, 0644)
+}
+
+// fileSum 文件 SHA-256
+func fileSum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,207 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/longyuan/lib.v3/compress"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/encrypt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verification 校验结果
+type Verification struct {
+	Manifest *Manifest
+	// Files 归档内的文件数量 (不含清单)
+	Files int
+	// Missing 清单中有但归档中不存在的文件
+	Missing []string
+	// Mismatch 大小或 SHA-256 不一致的文件
+	Mismatch []string
+	// Extra 归档中有但清单中不存在的文件
+	Extra []string
+}
+
+// Err 校验不通过时返回异常
+func (verification *Verification) Err() error {
+	var problems []string
+	if len(verification.Missing) > 0 {
+		problems = append(problems, "missing: "+strings.Join(verification.Missing, ", "))
+	}
+	if len(verification.Mismatch) > 0 {
+		problems = append(problems, "mismatch: "+strings.Join(verification.Mismatch, ", "))
+	}
+	if len(verification.Extra) > 0 {
+		problems = append(problems, "extra: "+strings.Join(verification.Extra, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("backup verify failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// verificationRecord 校验结果 (table 以外的输出格式)
+type verificationRecord struct {
+	Source   string         `json:"source" title:"来源"`
+	Name     string         `json:"name" title:"名称"`
+	Version  string         `json:"version" title:"程序版本号"`
+	Start    time.Time      `json:"start" title:"开始时间"`
+	End      time.Time      `json:"end" title:"结束时间"`
+	Counts   map[string]int `json:"counts" title:"对象数量"`
+	Files    int            `json:"files" title:"文件数量"`
+	Passed   bool           `json:"passed" title:"校验通过"`
+	Missing  []string       `json:"missing" title:"missing"`
+	Mismatch []string       `json:"mismatch" title:"mismatch"`
+	Extra    []string       `json:"extra" title:"extra"`
+}
+
+// Print 按 output 格式 (见 ctl.ParseOutput) 输出校验结果; table 时输出清单信息、对象数量和校验失败的文件
+func (verification *Verification) Print(output string) error {
+	output, err := ctl.ParseOutput(output)
+	if err != nil {
+		return err
+	}
+	var manifest = verification.Manifest
+	if output != ctl.OutputTable {
+		return ctl.Write(color.Output, output, verificationRecord{
+			Source:   manifest.Source,
+			Name:     manifest.Name,
+			Version:  manifest.Version,
+			Start:    manifest.Start,
+			End:      manifest.End,
+			Counts:   manifest.Counts,
+			Files:    verification.Files,
+			Passed:   verification.Err() == nil,
+			Missing:  verification.Missing,
+			Mismatch: verification.Mismatch,
+			Extra:    verification.Extra,
+		})
+	}
+	// 报告整体输出到 color.Output (标准输出, 已注册的密钥会被遮盖)
+	color.Blue("[Verify] %s %s (程序版本号: %s) %s ~ %s", manifest.Source, manifest.Name, manifest.Version,
+		manifest.Start.Format("2006-01-02 15:04:05"), manifest.End.Format("2006-01-02 15:04:05"))
+	var kinds []string
+	for kind := range manifest.Counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	var countTable [][]string
+	for _, kind := range kinds {
+		countTable = append(countTable, []string{kind, strconv.Itoa(manifest.Counts[kind])})
+	}
+	ctl.WriteTable(color.Output, []string{"对象类型", "数量"}, countTable)
+	if verification.Err() != nil {
+		var problemTable [][]string
+		for _, name := range verification.Missing {
+			problemTable = append(problemTable, []string{"missing", name})
+		}
+		for _, name := range verification.Mismatch {
+			problemTable = append(problemTable, []string{"mismatch", name})
+		}
+		for _, name := range verification.Extra {
+			problemTable = append(problemTable, []string{"extra", name})
+		}
+		ctl.WriteTable(color.Output, []string{"校验结果", "文件"}, problemTable)
+		return nil
+	}
+	color.Green("[Verify] 校验通过, 文件数量: %d", verification.Files)
+	return nil
+}
+
+// Verify 读取归档, 重新计算每个文件的 SHA-256 并与 manifest.json 比对
+func Verify(reader io.Reader, format compress.Format) (*Verification, error) {
+	var manifest *Manifest
+	var files = map[string]File{}
+	var names []string
+	err := compress.WalkReader(reader, format, func(entry compress.Entry, reader io.Reader) error {
+		// 清单只记录普通文件, zip 软链接的数据为链接目标, 不参与校验
+		if reader == nil || !entry.Mode.IsRegular() {
+			return nil
+		}
+		if entry.Name == ManifestName {
+			manifest = &Manifest{}
+			return json.NewDecoder(reader).Decode(manifest)
+		}
+		hash := sha256.New()
+		size, err := io.Copy(hash, reader)
+		if err != nil {
+			return err
+		}
+		files[entry.Name] = File{Name: entry.Name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}
+		names = append(names, entry.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("backup verify failed: %s not found", ManifestName)
+	}
+	var verification = Verification{Manifest: manifest, Files: len(files)}
+	var listed = map[string]bool{}
+	for _, item := range manifest.Files {
+		listed[item.Name] = true
+		file, ok := files[item.Name]
+		if !ok {
+			verification.Missing = append(verification.Missing, item.Name)
+			continue
+		}
+		if file.Size != item.Size || file.SHA256 != item.SHA256 {
+			verification.Mismatch = append(verification.Mismatch, item.Name)
+		}
+	}
+	for _, name := range names {
+		if !listed[name] {
+			verification.Extra = append(verification.Extra, name)
+		}
+	}
+	return &verification, nil
+}
+
+// VerifyFile 校验本地归档文件或 http(s) 地址; .age 后缀的归档先使用 identities 解密
+func VerifyFile(src string, identities []string) (*Verification, error) {
+	var name = strings.TrimSuffix(strings.SplitN(src, "?", 2)[0], encrypt.Ext)
+	format, err := compress.DetectFormat(name)
+	if err != nil {
+		return nil, err
+	}
+	var reader io.Reader
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		response, err := http.Get(src)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = response.Body.Close()
+		}()
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("download %s: %s", src, response.Status)
+		}
+		reader = response.Body
+	} else {
+		file, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		reader = file
+	}
+	if strings.HasSuffix(strings.SplitN(src, "?", 2)[0], encrypt.Ext) {
+		reader, err = encrypt.Reader(reader, identities)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Verify(reader, format)
+}
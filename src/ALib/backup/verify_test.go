@@ -0,0 +1,91 @@
+package backup
+
+import (
+	"bytes"
+	"github.com/fatih/color"
+	"github.com/longyuan/lib.v3/compress"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(test *testing.T) {
+	var src = test.TempDir()
+	err := os.MkdirAll(filepath.Join(src, "default"), 0755)
+	if err != nil {
+		test.Fatal(err)
+	}
+	for _, name := range []string{"a.yaml", "default/b.yaml"} {
+		err = os.WriteFile(filepath.Join(src, name), []byte(name), 0644)
+		if err != nil {
+			test.Fatal(err)
+		}
+	}
+	var manifest = NewManifest("kubernetes", "test")
+	manifest.Count("ConfigMap", 2)
+	err = manifest.Write(src)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(manifest.Files) != 2 || manifest.Files[1].Name != "default/b.yaml" {
+		test.Fatal(manifest.Files)
+	}
+	for _, format := range compress.Formats {
+		var buffer bytes.Buffer
+		err = compress.Archive(src, &buffer, format)
+		if err != nil {
+			test.Fatal(err)
+		}
+		verification, err := Verify(&buffer, format)
+		if err != nil || verification.Err() != nil {
+			test.Fatal(format, err, verification.Err())
+		}
+		if verification.Manifest.Counts["ConfigMap"] != 2 || verification.Files != 2 {
+			test.Fatal(format, verification)
+		}
+	}
+
+	// 修改、删除、新增文件
+	err = os.WriteFile(filepath.Join(src, "a.yaml"), []byte("changed"), 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = os.Remove(filepath.Join(src, "default", "b.yaml"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(src, "c.yaml"), []byte("c"), 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	var target = filepath.Join(test.TempDir(), "backup.tar.gz")
+	err = compress.Compress(src, target, compress.FormatTarGz, false)
+	if err != nil {
+		test.Fatal(err)
+	}
+	verification, err := VerifyFile(target, nil)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(verification.Mismatch) != 1 || len(verification.Missing) != 1 || len(verification.Extra) != 1 || verification.Err() == nil {
+		test.Fatal(verification)
+	}
+
+	// 表格报告整体输出到 color.Output
+	var output bytes.Buffer
+	var colorOutput, noColor = color.Output, color.NoColor
+	color.Output, color.NoColor = &output, true
+	defer func() {
+		color.Output, color.NoColor = colorOutput, noColor
+	}()
+	err = verification.Print("table")
+	if err != nil {
+		test.Fatal(err)
+	}
+	for _, item := range []string{"[Verify] kubernetes test", "ConfigMap", "mismatch", "a.yaml", "extra", "c.yaml"} {
+		if !strings.Contains(output.String(), item) {
+			test.Fatalf("missing %q in report: %s", item, output.String())
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package compress
+
+import (
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Format 归档格式
+type Format string
+
+const (
+	FormatZip    Format = "zip"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarZst Format = "tar.zst"
+)
+
+// Formats 支持的归档格式
+var Formats = []Format{FormatZip, FormatTarGz, FormatTarZst}
+
+// ParseFormat 解析归档格式 (zip, tar.gz, tgz, tar.zst, tzst); 为空时默认 zip
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), ".")) {
+	case "", "zip":
+		return FormatZip, nil
+	case "tar.gz", "tgz":
+		return FormatTarGz, nil
+	case "tar.zst", "tzst":
+		return FormatTarZst, nil
+	}
+	return "", fmt.Errorf("archive format not supported: %s", value)
+}
+
+// DetectFormat 根据文件名后缀识别归档格式
+func DetectFormat(fileName string) (Format, error) {
+	var name = strings.ToLower(fileName)
+	for _, format := range Formats {
+		if strings.HasSuffix(name, format.Ext()) {
+			return format, nil
+		}
+	}
+	if strings.HasSuffix(name, ".tgz") {
+		return FormatTarGz, nil
+	}
+	if strings.HasSuffix(name, ".tzst") {
+		return FormatTarZst, nil
+	}
+	return "", fmt.Errorf("archive format not supported: %s", fileName)
+}
+
+// Ext 文件后缀; 如 .tar.gz
+func (format Format) Ext() string {
+	return "." + string(format)
+}
+
+// Writer 归档写入
+type Writer interface {
+	// Add 写入一个文件或目录; name 归档内路径 (使用 / 分隔), reader 文件内容, 软链接为链接目标, 目录为 nil
+	Add(name string, info os.FileInfo, reader io.Reader) error
+	// Close 写入归档结尾并刷新压缩流 (不关闭底层 io.Writer)
+	Close() error
+}
+
+// NewWriter 创建归档写入; w 输出流
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatZip:
+		return newZipWriter(w), nil
+	case FormatTarGz:
+		return newTarGzWriter(w), nil
+	case FormatTarZst:
+		return newTarZstWriter(w)
+	}
+	return nil, fmt.Errorf("archive format not supported: %s", format)
+}
+
+// Compress 压缩目录到文件; src 源目录, target 输出文件, delete 是否删除源
+func Compress(src, target string, format Format, delete bool) error {
+	// 如果目标文件已存在删除
+	if _, err := os.Stat(target); err == nil || !os.IsNotExist(err) {
+		err := os.Remove(target)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 创建准备写入的文件
+	fw, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	err = archive(src, fw, format, path.Base(target))
+	if closeErr := fw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// 是否删除源文件
+	if delete {
+		err = os.RemoveAll(src)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Archive 压缩目录并写入 w (流式, 不生成本地文件); src 源目录
+func Archive(src string, w io.Writer, format Format) error {
+	return archive(src, w, format, "stream")
+}
+
+// archive 遍历目录写入归档; label 输出日志中的归档名称
+func archive(src string, w io.Writer, format Format, label string) error {
+	aw, err := NewWriter(format, w)
+	if err != nil {
+		return err
+	}
+
+	// 下面来将文件写入 aw ，因为有可能会有很多个目录及文件，所以递归处理
+	var startIndex = len(src)
+	err = filepath.Walk(src, func(filePath string, fi os.FileInfo, errBack error) error {
+		if errBack != nil {
+			return errBack
+		}
+		var name = filepath.ToSlash(strings.TrimPrefix(strings.TrimPrefix(filePath[startIndex:], "/"), "\\"))
+		if name == "" {
+			return nil
+		}
+
+		// 软链接写入链接目标
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(filePath)
+			if err != nil {
+				return err
+			}
+			return aw.Add(name, fi, strings.NewReader(link))
+		}
+
+		// 目录等只写入头信息
+		if !fi.Mode().IsRegular() {
+			return aw.Add(name, fi, nil)
+		}
+
+		// 打开要压缩的文件
+		fr, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = fr.Close()
+		}()
+		err = aw.Add(name, fi, fr)
+		if err != nil {
+			return err
+		}
+		// 输出压缩的内容
+		logger.Info("[%s]：%s <-- %s (%s)", format, label, filePath, fileSizeFormat(fi.Size()))
+		return nil
+	})
+	if closeErr := aw.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
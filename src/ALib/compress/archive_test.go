@@ -0,0 +1,158 @@
+package compress
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testDirectory 测试目录: a.txt, sub/b.txt
+func testDirectory(test *testing.T) string {
+	var src = test.TempDir()
+	err := os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte(strings.Repeat("b", 4096)), 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	return src
+}
+
+// tarEntries 读取 tar 归档内容
+func tarEntries(test *testing.T, reader io.Reader) map[string]string {
+	var entries = map[string]string{}
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			test.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			test.Fatal(err)
+		}
+		entries[header.Name] = string(data)
+	}
+	return entries
+}
+
+func keys(entries map[string]string) string {
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestArchive(test *testing.T) {
+	var src = testDirectory(test)
+	for _, format := range Formats {
+		var buffer bytes.Buffer
+		err := Archive(src, &buffer, format)
+		if err != nil {
+			test.Fatal(format, err)
+		}
+		var entries = map[string]string{}
+		switch format {
+		case FormatZip:
+			zr, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+			if err != nil {
+				test.Fatal(err)
+			}
+			for _, file := range zr.File {
+				fr, err := file.Open()
+				if err != nil {
+					test.Fatal(err)
+				}
+				data, _ := io.ReadAll(fr)
+				_ = fr.Close()
+				entries[file.Name] = string(data)
+			}
+		case FormatTarGz:
+			gr, err := gzip.NewReader(&buffer)
+			if err != nil {
+				test.Fatal(err)
+			}
+			entries = tarEntries(test, gr)
+		case FormatTarZst:
+			zr, err := zstd.NewReader(&buffer)
+			if err != nil {
+				test.Fatal(err)
+			}
+			entries = tarEntries(test, zr)
+			zr.Close()
+		}
+		if keys(entries) != "a.txt,sub/,sub/b.txt" {
+			test.Fatal(format, keys(entries))
+		}
+		if entries["a.txt"] != "a" || len(entries["sub/b.txt"]) != 4096 {
+			test.Fatal(format, "content error")
+		}
+	}
+}
+
+func TestCompress(test *testing.T) {
+	var src = testDirectory(test)
+	var target = filepath.Join(test.TempDir(), "backup"+FormatTarGz.Ext())
+	err := Compress(src, target, FormatTarGz, true)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if _, err = os.Stat(src); !os.IsNotExist(err) {
+		test.Fatal("source not deleted")
+	}
+	format, err := DetectFormat(target)
+	if err != nil || format != FormatTarGz {
+		test.Fatal(format, err)
+	}
+	if _, err = ParseFormat("rar"); err == nil {
+		test.Fatal("rar should not be supported")
+	}
+}
+
+func TestArchiveSymlink(test *testing.T) {
+	var src = testDirectory(test)
+	err := os.Symlink("a.txt", filepath.Join(src, "link"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	for _, format := range Formats {
+		var buffer bytes.Buffer
+		err = Archive(src, &buffer, format)
+		if err != nil {
+			test.Fatal(format, err)
+		}
+		var target = filepath.Join(test.TempDir(), "out")
+		var reader = bytes.NewReader(buffer.Bytes())
+		err = Extract(reader, reader.Size(), format, target)
+		if err != nil {
+			test.Fatal(format, err)
+		}
+		link, err := os.Readlink(filepath.Join(target, "link"))
+		if err != nil || link != "a.txt" {
+			test.Fatal(format, link, err)
+		}
+		data, err := os.ReadFile(filepath.Join(target, "link"))
+		if err != nil || string(data) != "a" {
+			test.Fatal(format, string(data), err)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package compress
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/longyuan/lib.v3/logger"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Entry 归档内的文件
+type Entry struct {
+	// Name 归档内路径 (使用 / 分隔, 目录不带结尾 /)
+	Name    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	// Link 链接目标 (软链接为链接内容, 硬链接为归档内路径)
+	Link string
+}
+
+// IsDir 是否目录
+func (entry Entry) IsDir() bool {
+	return entry.Mode.IsDir()
+}
+
+// Walk 顺序读取归档内的文件; fn 的 reader 只在回调内有效, 目录、链接为 nil
+func Walk(reader io.ReaderAt, size int64, format Format, fn func(entry Entry, reader io.Reader) error) error {
+	switch format {
+	case FormatZip:
+		zr, err := zip.NewReader(reader, size)
+		if err != nil {
+			return err
+		}
+		for _, file := range zr.File {
+			var entry = Entry{
+				Name:    strings.TrimSuffix(file.Name, "/"),
+				Size:    int64(file.UncompressedSize64),
+				Mode:    file.Mode(),
+				ModTime: file.Modified,
+			}
+			// 软链接的数据为链接目标, 同普通文件一起读取
+			if !entry.Mode.IsRegular() && entry.Mode&os.ModeSymlink == 0 {
+				err = fn(entry, nil)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			fr, err := file.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(entry, fr)
+			_ = fr.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatTarGz, FormatTarZst:
+		return walkStream(io.NewSectionReader(reader, 0, size), format, fn)
+	}
+	return fmt.Errorf("archive format not supported: %s", format)
+}
+
+// WalkReader 从流顺序读取归档 (如解密后的内容、下载的对象); zip 格式需要随机读取, 会先写入临时文件
+func WalkReader(reader io.Reader, format Format, fn func(entry Entry, reader io.Reader) error) error {
+	if format != FormatZip {
+		return walkStream(reader, format, fn)
+	}
+	temp, err := os.CreateTemp("", "walk_*"+format.Ext())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = temp.Close()
+		_ = os.Remove(temp.Name())
+	}()
+	size, err := io.Copy(temp, reader)
+	if err != nil {
+		return err
+	}
+	return Walk(temp, size, format, fn)
+}
+
+// walkStream 读取 tar 归档, 外层为压缩流 (gzip, zstd)
+func walkStream(reader io.Reader, format Format, fn func(entry Entry, reader io.Reader) error) error {
+	switch format {
+	case FormatTarGz:
+		gr, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = gr.Close()
+		}()
+		return walkTar(gr, fn)
+	case FormatTarZst:
+		zr, err := zstd.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return walkTar(zr, fn)
+	}
+	return fmt.Errorf("archive format not supported: %s", format)
+}
+
+func walkTar(reader io.Reader, fn func(entry Entry, reader io.Reader) error) error {
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var entry = Entry{
+			Name:    strings.TrimSuffix(header.Name, "/"),
+			Size:    header.Size,
+			Mode:    header.FileInfo().Mode(),
+			ModTime: header.ModTime,
+			Link:    header.Linkname,
+		}
+		var content io.Reader
+		if entry.Mode.IsRegular() {
+			content = tr
+		}
+		err = fn(entry, content)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// List 列出归档内的文件
+func List(reader io.ReaderAt, size int64, format Format) ([]Entry, error) {
+	var entries []Entry
+	err := Walk(reader, size, format, func(entry Entry, reader io.Reader) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ExtractEntry 读取归档内的单个文件并写入 w; name 归档内路径
+func ExtractEntry(reader io.ReaderAt, size int64, format Format, name string, w io.Writer) (*Entry, error) {
+	name = strings.TrimSuffix(strings.TrimPrefix(path.Clean("/"+name), "/"), "/")
+	var result *Entry
+	err := Walk(reader, size, format, func(entry Entry, reader io.Reader) error {
+		if result != nil || entry.Name != name {
+			return nil
+		}
+		if reader == nil {
+			return fmt.Errorf("archive entry is not a file: %s", name)
+		}
+		_, err := io.Copy(w, reader)
+		if err != nil {
+			return err
+		}
+		result = &entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("archive entry not found: %s", name)
+	}
+	return result, nil
+}
+
+// Extract 解压归档到 target 目录; 保留权限和修改时间, 拒绝路径穿越 (zip-slip) 的文件
+//
+// 软链接的目标不能是绝对路径或包含 .., 也不解压路径经过已解压软链接的文件 (如 d -> . 后的 d/e -> ..),
+// 创建上级目录前再解析其中的软链接, 确认仍在 target 内
+func Extract(reader io.ReaderAt, size int64, format Format, target string) error {
+	err := os.MkdirAll(target, 0755)
+	if err != nil {
+		return err
+	}
+	target, err = filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	target, err = filepath.EvalSymlinks(target)
+	if err != nil {
+		return err
+	}
+	// 目录的修改时间在所有文件写入后设置
+	var directories []Entry
+	// 已解压的软链接 (归档内路径)
+	var links = map[string]bool{}
+	err = Walk(reader, size, format, func(entry Entry, reader io.Reader) error {
+		localPath, err := safePath(target, entry.Name)
+		if err != nil {
+			return err
+		}
+		if throughLink(links, entry.Name) {
+			return fmt.Errorf("archive entry %s: illegal path through link", entry.Name)
+		}
+		err = insideTarget(target, filepath.Dir(localPath))
+		if err != nil {
+			return fmt.Errorf("archive entry %s: %w", entry.Name, err)
+		}
+		err = os.MkdirAll(filepath.Dir(localPath), 0755)
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			// 目录至少保留所有者的读写执行权限, 否则无法写入其中的文件
+			err = os.MkdirAll(localPath, 0755)
+			if err != nil {
+				return err
+			}
+			err = os.Chmod(localPath, entry.Mode.Perm()|0700)
+			if err != nil {
+				return err
+			}
+			directories = append(directories, entry)
+			return nil
+		case entry.Mode&os.ModeSymlink != 0:
+			var link = entry.Link
+			if link == "" && reader != nil {
+				data, err := io.ReadAll(reader)
+				if err != nil {
+					return err
+				}
+				link = string(data)
+			}
+			if filepath.IsAbs(link) {
+				return fmt.Errorf("archive entry %s: illegal link: %s", entry.Name, link)
+			}
+			_, err = safePath(target, path.Join(path.Dir(entry.Name), filepath.ToSlash(link)))
+			if err != nil {
+				return err
+			}
+			for _, part := range strings.Split(filepath.ToSlash(link), "/") {
+				if part == ".." {
+					return fmt.Errorf("archive entry %s: illegal link: %s", entry.Name, link)
+				}
+			}
+			_ = os.Remove(localPath)
+			links[cleanName(entry.Name)] = true
+			return os.Symlink(link, localPath)
+		case entry.Link != "":
+			// 硬链接, Link 为归档内路径
+			linkPath, err := safePath(target, entry.Link)
+			if err != nil {
+				return err
+			}
+			if throughLink(links, entry.Link) || links[cleanName(entry.Link)] {
+				return fmt.Errorf("archive entry %s: illegal link: %s", entry.Name, entry.Link)
+			}
+			_ = os.Remove(localPath)
+			return os.Link(linkPath, localPath)
+		case !entry.Mode.IsRegular():
+			// 设备、管道等不解压
+			return nil
+		}
+		err = writeFile(localPath, entry, reader)
+		if err != nil {
+			return err
+		}
+		logger.Info("[Extract]：%s --> %s (%s)", entry.Name, localPath, fileSizeFormat(entry.Size))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for index := len(directories) - 1; index >= 0; index-- {
+		localPath, _ := safePath(target, directories[index].Name)
+		err = os.Chtimes(localPath, directories[index].ModTime, directories[index].ModTime)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractReader 从流解压归档 (如下载的对象); zip 格式需要随机读取, 会先写入临时文件
+func ExtractReader(reader io.Reader, format Format, target string) error {
+	if readerAt, ok := reader.(interface {
+		io.ReaderAt
+		Stat() (os.FileInfo, error)
+	}); ok {
+		info, err := readerAt.Stat()
+		if err != nil {
+			return err
+		}
+		return Extract(readerAt, info.Size(), format, target)
+	}
+	temp, err := os.CreateTemp("", "extract_*"+format.Ext())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = temp.Close()
+		_ = os.Remove(temp.Name())
+	}()
+	size, err := io.Copy(temp, reader)
+	if err != nil {
+		return err
+	}
+	return Extract(temp, size, format, target)
+}
+
+// Unzip 解压归档文件; 根据文件后缀识别格式 (zip, tar.gz, tar.zst), src 归档文件, target 输出目录
+func Unzip(src, target string) error {
+	format, err := DetectFormat(src)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return Extract(file, info.Size(), format, target)
+}
+
+// safePath 归档内路径对应的本地路径; 绝对路径或跳出 target 的路径返回异常
+func safePath(target, name string) (string, error) {
+	var slashName = strings.ReplaceAll(name, "\\", "/")
+	if slashName == "" || strings.HasPrefix(slashName, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("archive entry illegal path: %s", name)
+	}
+	var localPath = filepath.Join(target, filepath.FromSlash(slashName))
+	relative, err := filepath.Rel(target, localPath)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry illegal path: %s", name)
+	}
+	return localPath, nil
+}
+
+// cleanName 归档内路径统一为 / 分隔的相对路径
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+}
+
+// throughLink name 的上级目录中是否有已解压的软链接
+func throughLink(links map[string]bool, name string) bool {
+	for parent := path.Dir(cleanName(name)); parent != "." && parent != "/"; parent = path.Dir(parent) {
+		if links[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// insideTarget 解析 directory (不存在时为最近的已存在上级目录) 中的软链接后是否仍在 target (已解析软链接) 内
+func insideTarget(target, directory string) error {
+	resolved, err := filepath.EvalSymlinks(directory)
+	for err != nil && os.IsNotExist(err) && directory != target && directory != filepath.Dir(directory) {
+		directory = filepath.Dir(directory)
+		resolved, err = filepath.EvalSymlinks(directory)
+	}
+	if err != nil {
+		return err
+	}
+	relative, err := filepath.Rel(target, resolved)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("illegal path outside target: %s", resolved)
+	}
+	return nil
+}
+
+// writeFile 写入文件并设置权限和修改时间
+func writeFile(localPath string, entry Entry, reader io.Reader) error {
+	_ = os.Remove(localPath)
+	file, err := os.OpenFile(localPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, entry.Mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	// umask 会影响创建时的权限
+	err = os.Chmod(localPath, entry.Mode.Perm())
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(localPath, entry.ModTime, entry.ModTime)
+}
@@ -0,0 +1,141 @@
+package compress
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtract(test *testing.T) {
+	var src = testDirectory(test)
+	var modTime = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	err := os.Chmod(filepath.Join(src, "a.txt"), 0600)
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = os.Chtimes(filepath.Join(src, "sub", "b.txt"), modTime, modTime)
+	if err != nil {
+		test.Fatal(err)
+	}
+	for _, format := range Formats {
+		var buffer bytes.Buffer
+		err = Archive(src, &buffer, format)
+		if err != nil {
+			test.Fatal(format, err)
+		}
+		var reader = bytes.NewReader(buffer.Bytes())
+
+		entries, err := List(reader, reader.Size(), format)
+		if err != nil || len(entries) != 3 || !entries[1].IsDir() || entries[1].Name != "sub" {
+			test.Fatal(format, entries, err)
+		}
+
+		var content bytes.Buffer
+		entry, err := ExtractEntry(reader, reader.Size(), format, "sub/b.txt", &content)
+		if err != nil || content.Len() != 4096 || entry.Size != 4096 {
+			test.Fatal(format, err)
+		}
+		_, err = ExtractEntry(reader, reader.Size(), format, "c.txt", &content)
+		if err == nil {
+			test.Fatal(format, "c.txt should not exist")
+		}
+
+		var target = test.TempDir()
+		err = Extract(reader, reader.Size(), format, target)
+		if err != nil {
+			test.Fatal(format, err)
+		}
+		info, err := os.Stat(filepath.Join(target, "a.txt"))
+		if err != nil || info.Mode().Perm() != 0600 {
+			test.Fatal(format, info.Mode(), err)
+		}
+		info, err = os.Stat(filepath.Join(target, "sub", "b.txt"))
+		if err != nil || !info.ModTime().Equal(modTime) {
+			test.Fatal(format, info.ModTime(), err)
+		}
+	}
+}
+
+func TestExtractZipSlip(test *testing.T) {
+	var zipBuffer bytes.Buffer
+	zw := zip.NewWriter(&zipBuffer)
+	_, err := zw.Create("../evil.txt")
+	if err != nil {
+		test.Fatal(err)
+	}
+	_ = zw.Close()
+
+	var tarBuffer bytes.Buffer
+	gw := gzip.NewWriter(&tarBuffer)
+	tw := tar.NewWriter(gw)
+	_ = tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../etc", Mode: 0777})
+	_ = tw.Close()
+	_ = gw.Close()
+
+	var target = test.TempDir()
+	var reader = bytes.NewReader(zipBuffer.Bytes())
+	err = Extract(reader, reader.Size(), FormatZip, filepath.Join(target, "out"))
+	if err == nil || !strings.Contains(err.Error(), "illegal path") {
+		test.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(target, "evil.txt")); !os.IsNotExist(err) {
+		test.Fatal("zip slip")
+	}
+	reader = bytes.NewReader(tarBuffer.Bytes())
+	err = Extract(reader, reader.Size(), FormatTarGz, filepath.Join(target, "out"))
+	if err == nil || !strings.Contains(err.Error(), "illegal path") {
+		test.Fatal(err)
+	}
+}
+
+func TestExtractSymlinkChain(test *testing.T) {
+	var tarBuffer bytes.Buffer
+	gw := gzip.NewWriter(&tarBuffer)
+	tw := tar.NewWriter(gw)
+	_ = tw.WriteHeader(&tar.Header{Name: "d", Typeflag: tar.TypeSymlink, Linkname: ".", Mode: 0777})
+	_ = tw.WriteHeader(&tar.Header{Name: "d/e", Typeflag: tar.TypeSymlink, Linkname: "..", Mode: 0777})
+	_ = tw.WriteHeader(&tar.Header{Name: "d/e/pwned.txt", Typeflag: tar.TypeReg, Size: 5, Mode: 0644})
+	_, _ = tw.Write([]byte("pwned"))
+	_ = tw.Close()
+	_ = gw.Close()
+
+	var root = test.TempDir()
+	var reader = bytes.NewReader(tarBuffer.Bytes())
+	err := Extract(reader, reader.Size(), FormatTarGz, filepath.Join(root, "out"))
+	if err == nil || !strings.Contains(err.Error(), "illegal") {
+		test.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "pwned.txt")); !os.IsNotExist(err) {
+		test.Fatal("symlink chain escaped target")
+	}
+
+	// 已存在的软链接指向 target 外时不写入
+	var outside = filepath.Join(root, "outside")
+	var target = filepath.Join(root, "target")
+	_ = os.MkdirAll(outside, 0755)
+	_ = os.MkdirAll(target, 0755)
+	if err = os.Symlink(outside, filepath.Join(target, "x")); err != nil {
+		test.Fatal(err)
+	}
+	tarBuffer.Reset()
+	gw = gzip.NewWriter(&tarBuffer)
+	tw = tar.NewWriter(gw)
+	_ = tw.WriteHeader(&tar.Header{Name: "x/y/z.txt", Typeflag: tar.TypeReg, Size: 1, Mode: 0644})
+	_, _ = tw.Write([]byte("z"))
+	_ = tw.Close()
+	_ = gw.Close()
+	reader = bytes.NewReader(tarBuffer.Bytes())
+	err = Extract(reader, reader.Size(), FormatTarGz, target)
+	if err == nil || !strings.Contains(err.Error(), "illegal path") {
+		test.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(outside, "y")); !os.IsNotExist(err) {
+		test.Fatal("existing symlink escaped target")
+	}
+}
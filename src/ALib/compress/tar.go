@@ -0,0 +1,67 @@
+package compress
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+)
+
+// tarWriter tar 归档, 外层为压缩流 (gzip, zstd)
+type tarWriter struct {
+	tw *tar.Writer
+	cw io.WriteCloser
+}
+
+func newTarGzWriter(w io.Writer) Writer {
+	var cw = gzip.NewWriter(w)
+	return &tarWriter{tw: tar.NewWriter(cw), cw: cw}
+}
+
+func newTarZstWriter(w io.Writer) (Writer, error) {
+	cw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &tarWriter{tw: tar.NewWriter(cw), cw: cw}, nil
+}
+
+func (writer *tarWriter) Add(name string, info os.FileInfo, reader io.Reader) error {
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 && reader != nil {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		link = string(data)
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	// 不记录用户名, 归档在不同机器上保持一致
+	header.Uname = ""
+	header.Gname = ""
+	err = writer.tw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	if reader == nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	_, err = io.Copy(writer.tw, reader)
+	return err
+}
+
+func (writer *tarWriter) Close() error {
+	err := writer.tw.Close()
+	if closeErr := writer.cw.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
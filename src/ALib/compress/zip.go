@@ -4,111 +4,52 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path"
-	"path/filepath"
-	"strings"
 )
 import "archive/zip"
 
 // Zip 压缩文件; src 源目录, target 输出文件, delete 是否删除源
 func Zip(src, target string, delete bool) error {
-	// 如果目标文件已存在删除
-	if _, err := os.Stat(target); err == nil || !os.IsNotExist(err) {
-		err := os.Remove(target)
-		if err != nil {
-			return err
-		}
-	}
+	return Compress(src, target, FormatZip, delete)
+}
+
+// zipWriter zip 归档
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func newZipWriter(w io.Writer) Writer {
+	return &zipWriter{zw: zip.NewWriter(w)}
+}
 
-	// 创建准备写入的文件
-	fw, err := os.Create(target)
-	defer func(fw *os.File) {
-		err := fw.Close()
-		if err != nil {
-			panic(err)
-		}
-	}(fw)
+func (writer *zipWriter) Add(name string, info os.FileInfo, reader io.Reader) error {
+	// 通过文件信息，创建 zip 的文件信息
+	fh, err := zip.FileInfoHeader(info)
 	if err != nil {
 		return err
 	}
+	fh.Name = name
+	// 这步开始没有加，会发现解压的时候说它不是个目录
+	if info.IsDir() {
+		fh.Name += "/"
+	}
 
-	// 通过 fw 来创建 zip.Write
-	zw := zip.NewWriter(fw)
-	defer func() {
-		// 检测一下是否成功关闭
-		if err := zw.Close(); err != nil {
-			panic(err)
-		}
-	}()
-
-	// 下面来将文件写入 zw ，因为有可能会有很多个目录及文件，所以递归处理
-	var startIndex = len(src)
-	err = filepath.Walk(src, func(filePath string, fi os.FileInfo, errBack error) (err error) {
-		if errBack != nil {
-			return errBack
-		}
-
-		// 通过文件信息，创建 zip 的文件信息
-		fh, err := zip.FileInfoHeader(fi)
-		if err != nil {
-			return err
-		}
-
-		// 替换文件信息中的文件名
-		fh.Name = strings.TrimPrefix(strings.TrimPrefix(filePath[startIndex:], "/"), "\\")
-		if fh.Name == "" {
-			return nil
-		}
-
-		// 这步开始没有加，会发现解压的时候说它不是个目录
-		if fi.IsDir() {
-			fh.Name += "/"
-		}
-
-		// 写入文件信息，并返回一个 Write 结构
-		w, err := zw.CreateHeader(fh)
-		if err != nil {
-			return err
-		}
-
-		// 检测，如果不是标准文件就只写入头信息，不写入文件数据到 w
-		// 如目录，也没有数据需要写
-		if !fh.Mode().IsRegular() {
-			return nil
-		}
-
-		// 打开要压缩的文件
-		fr, err := os.Open(filePath)
-		defer func(fr *os.File) {
-			err := fr.Close()
-			if err != nil {
-				panic(err)
-			}
-		}(fr)
-		if err != nil {
-			return err
-		}
-
-		// 将打开的文件 Copy 到 w
-		fileSize, err := io.Copy(w, fr)
-		if err != nil {
-			return err
-		}
-		// 输出压缩的内容
-		fmt.Printf("[Zip]：%s <-- %s (%s)\n", path.Base(target), filePath, fileSizeFormat(fileSize))
-		return nil
-	})
+	// 写入文件信息，并返回一个 Write 结构
+	w, err := writer.zw.CreateHeader(fh)
 	if err != nil {
 		return err
 	}
-	// 是否删除源文件
-	if delete {
-		err = os.RemoveAll(src)
-		if err != nil {
-			return err
-		}
+
+	// 检测，如果不是标准文件就只写入头信息，不写入文件数据到 w
+	// 如目录，也没有数据需要写; 软链接的数据为链接目标
+	if reader == nil || !fh.Mode().IsRegular() && fh.Mode()&os.ModeSymlink == 0 {
+		return nil
 	}
-	return nil
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+func (writer *zipWriter) Close() error {
+	return writer.zw.Close()
 }
 
 // fileSizeFormat 文件大小格式化
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"github.com/longyuan/lib.v3/scheduler"
+	"github.com/longyuan/lib.v3/secret"
+	"os"
+	"time"
+)
+
+// Daemon 调度服务配置; 一个进程运行多个任务, 任务格式同 Job (必须配置 name)
+//
+//	history: /var/lib/longyuan/history.json
+//	stopTimeout: 10m
+//	listen: "127.0.0.1:9100"
+//	token: env:LONGYUAN_API_TOKEN
+//	jobs:
+//	  - name: nacos-prod
+//	    schedule: "0 2 * * *"
+//	    sources: [{name: prod, type: nacos, host: http://127.0.0.1:8848, username: nacos, password: vault:nacos-prod}]
+//	    storage: {targets: [vault:cos-backup]}
+//	  - name: gitlab
+//	    schedule: "0 3 * * *"
+//	    sourceDir: /etc/longyuan/gitlab
+//	    sourceType: gitlab
+//	    storage: {targets: [vault:cos-backup]}
+type Daemon struct {
+	// History 运行记录文件, 为空时只保存在内存
+	History string `yaml:"history" json:"history"`
+	// HistoryLimit 每个任务保留的运行记录数量, 默认 100
+	HistoryLimit int `yaml:"historyLimit" json:"historyLimit"`
+	// StopTimeout 收到 SIGTERM 后等待运行中任务结束的时间 (如 10m), 超时后取消任务; 默认 1m
+	StopTimeout string `yaml:"stopTimeout" json:"stopTimeout"`
+	// Listen 状态接口地址 (如 127.0.0.1:9100), 提供 /healthz, /jobs, /metrics 和触发任务的 POST /jobs/{name}/run;
+	// 只有触发任务需要令牌 (见 Token), 应只监听内网地址. 为空时不启动
+	Listen string `yaml:"listen" json:"listen"`
+	// Token 触发任务接口的令牌或密钥引用 (env:, file:, vault:); 为空时使用环境变量 LONGYUAN_API_TOKEN, 都为空时不允许触发
+	Token string `yaml:"token" json:"token"`
+	Jobs  []Job  `yaml:"jobs" json:"jobs"`
+}
+
+// APIToken 触发任务接口的令牌; 解析密钥引用, 未配置时使用环境变量 LONGYUAN_API_TOKEN
+func (daemon *Daemon) APIToken() (string, error) {
+	if daemon.Token == "" {
+		return os.Getenv(scheduler.TokenEnv), nil
+	}
+	return secret.Resolve(daemon.Token)
+}
+
+// LoadDaemon 读取调度服务配置文件 (YAML/JSON); 展开环境变量和密钥引用, 不允许未定义的字段, 并校验配置
+func LoadDaemon(configPath string) (*Daemon, error) {
+	fileBytes, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	daemon, err := ParseDaemon(fileBytes)
+	if err != nil {
+		return nil, fmt.Errorf("daemon config %s: %w", configPath, err)
+	}
+	return daemon, nil
+}
+
+// ParseDaemon 解析调度服务配置 (YAML/JSON) 并校验
+func ParseDaemon(data []byte) (*Daemon, error) {
+	var daemon Daemon
+	err := decode(data, &daemon, true)
+	if err != nil {
+		return nil, err
+	}
+	err = daemon.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &daemon, nil
+}
+
+// Validate 校验配置, 返回所有任务的问题; 如 jobs[0].schedule: required
+func (daemon *Daemon) Validate() error {
+	var problems validation
+	if len(daemon.Jobs) == 0 {
+		problems.add("jobs", "required")
+	}
+	if daemon.HistoryLimit < 0 {
+		problems.add("historyLimit", "must not be negative")
+	}
+	if daemon.StopTimeout != "" {
+		if _, err := time.ParseDuration(daemon.StopTimeout); err != nil {
+			problems.add("stopTimeout", err.Error())
+		}
+	}
+	var names = map[string]bool{}
+	for index := range daemon.Jobs {
+		var job = &daemon.Jobs[index]
+		var prefix = fmt.Sprintf("jobs[%d].", index)
+		job.validate(prefix, &problems)
+		if job.Name == "" {
+			problems.add("name", "required")
+		} else if names[job.Name] {
+			problems.add("name", "duplicate: "+job.Name)
+		}
+		names[job.Name] = true
+		if job.SourceDir != "" && job.SourceType == "" {
+			problems.add("sourceType", "required with sourceDir")
+		}
+	}
+	return problems.err()
+}
+
+// Timeout 停止超时
+func (daemon *Daemon) Timeout() time.Duration {
+	timeout, err := time.ParseDuration(daemon.StopTimeout)
+	if err != nil || timeout <= 0 {
+		return scheduler.DefaultStopTimeout
+	}
+	return timeout
+}
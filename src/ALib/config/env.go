@@ -0,0 +1,101 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/longyuan/lib.v3/secret"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envPattern ${NAME} 或 ${NAME:-默认值}; $${NAME} 不展开
+var envPattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Expand 展开字符串中的环境变量; 未定义且没有默认值时返回异常
+func Expand(value string) (string, error) {
+	var missing []string
+	var result = envPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		var groups = envPattern.FindStringSubmatch(match)
+		if env, ok := os.LookupEnv(groups[1]); ok {
+			return env
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+		missing = append(missing, groups[1])
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable not set: %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
+
+// expandNode 展开所有字符串值中的环境变量 (不展开 Key), 再解析密钥引用 (env:, file:, vault:); 异常包含行号
+func expandNode(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var value = node.Value
+		var err error
+		if strings.Contains(value, "${") {
+			value, err = Expand(value)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", node.Line, err)
+			}
+		}
+		if secret.IsReference(value) {
+			value, err = secret.Resolve(value)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", node.Line, err)
+			}
+		}
+		if value == node.Value {
+			return nil
+		}
+		node.Value = value
+		// 展开后按字符串处理, 避免引号丢失后类型变化
+		if node.Tag == "!!str" || node.Style != 0 {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+		return nil
+	}
+	for index, child := range node.Content {
+		if node.Kind == yaml.MappingNode && index%2 == 0 {
+			continue
+		}
+		err := expandNode(child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode 展开环境变量和密钥引用后解析 YAML/JSON; strict 不允许未定义的字段
+func decode(data []byte, value any, strict bool) error {
+	var node yaml.Node
+	err := yaml.Unmarshal(data, &node)
+	if err != nil {
+		return err
+	}
+	err = expandNode(&node)
+	if err != nil {
+		return err
+	}
+	expanded, err := yaml.Marshal(&node)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(expanded))
+	decoder.KnownFields(strict)
+	err = decoder.Decode(value)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// AddCronFlags 为定时命令添加参数 (--run-now, --listen, --job, --config, --cron, --notice), 见 CronJob;
+// 备份来源添加存储、归档、保留策略和上传参数, 域名来源添加 --state 和 --digest
+func AddCronFlags(cmd *cobra.Command, sourceType string) {
+	var flags = cmd.Flags()
+	flags.Bool("run-now", false, "Run The Job Once Immediately, Then Follow The Schedule")
+	flags.String("listen", "", "Status API Address (e.g. 127.0.0.1:9100) Serving /healthz, /jobs and /metrics; POST /jobs/{name}/run Requires Bearer Token From LONGYUAN_API_TOKEN")
+	if sourceType == SourceDomain {
+		flags.StringP("job", "j", "", "Job Config YAML/JSON (schedule, sources, state, digestHour, notice); Replaces The Flags Below")
+		flags.StringP("config", "c", "", "Domain Config")
+		flags.String("cron", "", "Cron")
+		flags.String("state", "", "State File (only notify on state change)")
+		flags.Int("digest", -1, "Daily Digest Hour (0-23, requires --state)")
+		flags.StringP("notice", "n", "", "Notice Config (CP_WECHAT|DINGTALK|FEISHU|LARK|SLACK|EMAIL|WEBHOOK),URL[,Secret] or Route Config YAML")
+		return
+	}
+	flags.StringP("job", "j", "", "Job Config YAML/JSON (schedule, sources, storage, archive, notice); Replaces The Flags Below")
+	flags.StringP("config", "c", "", "Config Path")
+	flags.String("cron", "", "Cron")
+	flags.StringArray("cloud-storage", nil, "CloudStorage Config (cos|s3|oss|file)://...[?storage-class=ARCHIVE&sse=kms&tag=key:value] or URL,SecretId,SecretKey; Repeat For Multiple Targets")
+	flags.String("replica-policy", "all", "Multiple Targets Success Policy (all|any|quorum)")
+	flags.StringP("notice", "n", "", "Notice Config (CP_WECHAT|DINGTALK|FEISHU|LARK|SLACK|EMAIL|WEBHOOK),URL[,Secret] or Route Config YAML")
+	flags.String("format", "zip", "Archive Format (zip|tar.gz|tar.zst)")
+	flags.StringSlice("encrypt-recipient", nil, "Encrypt Recipient (age1... Public Key, Recipients File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
+	flags.Int("keep-last", 0, "Retention: Keep Last N Backups")
+	flags.Int("keep-daily", 0, "Retention: Keep N Daily Backups")
+	flags.Int("keep-weekly", 0, "Retention: Keep N Weekly Backups")
+	flags.Int("keep-monthly", 0, "Retention: Keep N Monthly Backups")
+	flags.Bool("dry-run", false, "Retention: Only Log Backups To Prune")
+	flags.Int("upload-concurrency", 4, "Upload: Concurrent Multipart Uploads")
+	flags.String("bandwidth-limit", "", "Upload: Bandwidth Limit Per Second (e.g. 512K, 10M)")
+}
+
+// CronJob 由 AddCronFlags 添加的参数创建任务并校验; 指定 --job 时读取任务配置文件,
+// 否则 --config 为来源配置目录 (域名来源为域名列表文件, 并且必须配置 --notice)
+func CronJob(cmd *cobra.Command, sourceType string) (*Job, error) {
+	var flags = cmd.Flags()
+	jobPath, err := flags.GetString("job")
+	if err != nil {
+		return nil, err
+	}
+	if jobPath != "" {
+		return Load(jobPath)
+	}
+	configPath, err := flags.GetString("config")
+	if err != nil {
+		return nil, err
+	}
+	if configPath == "" {
+		return nil, fmt.Errorf("ConfigPath Required")
+	}
+	var job Job
+	job.Schedule, err = flags.GetString("cron")
+	if err != nil {
+		return nil, err
+	}
+	job.Notice.Config, err = flags.GetString("notice")
+	if err != nil {
+		return nil, err
+	}
+	if sourceType == SourceDomain {
+		if job.Notice.Config == "" {
+			return nil, fmt.Errorf("Notice Required")
+		}
+		job.Sources = []Source{{Name: "domain", Type: SourceDomain, File: configPath}}
+		job.State, err = flags.GetString("state")
+		if err != nil {
+			return nil, err
+		}
+		digest, err := flags.GetInt("digest")
+		if err != nil {
+			return nil, err
+		}
+		if digest >= 0 {
+			job.DigestHour = &digest
+		}
+	} else {
+		job.SourceDir = configPath
+		job.Storage.Targets, err = flags.GetStringArray("cloud-storage")
+		if err != nil {
+			return nil, err
+		}
+		job.Storage.Policy, err = flags.GetString("replica-policy")
+		if err != nil {
+			return nil, err
+		}
+		job.Archive.Format, err = flags.GetString("format")
+		if err != nil {
+			return nil, err
+		}
+		job.Archive.Recipients, err = flags.GetStringSlice("encrypt-recipient")
+		if err != nil {
+			return nil, err
+		}
+		job.Storage.Retention.KeepLast, err = flags.GetInt("keep-last")
+		if err != nil {
+			return nil, err
+		}
+		job.Storage.Retention.KeepDaily, err = flags.GetInt("keep-daily")
+		if err != nil {
+			return nil, err
+		}
+		job.Storage.Retention.KeepWeekly, err = flags.GetInt("keep-weekly")
+		if err != nil {
+			return nil, err
+		}
+		job.Storage.Retention.KeepMonthly, err = flags.GetInt("keep-monthly")
+		if err != nil {
+			return nil, err
+		}
+		job.Storage.Retention.DryRun, err = flags.GetBool("dry-run")
+		if err != nil {
+			return nil, err
+		}
+		job.Storage.Upload.Concurrency, err = flags.GetInt("upload-concurrency")
+		if err != nil {
+			return nil, err
+		}
+		job.Storage.Upload.BandwidthLimit, err = flags.GetString("bandwidth-limit")
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = job.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
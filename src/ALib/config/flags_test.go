@@ -0,0 +1,44 @@
+package config
+
+import (
+	"github.com/spf13/cobra"
+	"testing"
+)
+
+func TestCronJob(test *testing.T) {
+	var cmd = &cobra.Command{Use: "cron-backup"}
+	AddCronFlags(cmd, SourceNacos)
+	err := cmd.ParseFlags([]string{"-c", "/etc/nacos", "--cron", "0 2 * * *", "--cloud-storage", "file:///backup", "--keep-daily", "7", "--encrypt-recipient", "pass:secret"})
+	if err != nil {
+		test.Fatal(err)
+	}
+	job, err := CronJob(cmd, SourceNacos)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if job.SourceDir != "/etc/nacos" || job.Schedule != "0 2 * * *" || job.Storage.Targets[0] != "file:///backup" || job.Storage.Retention.KeepDaily != 7 || job.Archive.Format != "zip" || job.Storage.Upload.Concurrency != 4 {
+		test.Fatal(job)
+	}
+
+	cmd = &cobra.Command{Use: "cron"}
+	AddCronFlags(cmd, SourceDomain)
+	if cmd.Flags().Lookup("cloud-storage") != nil {
+		test.Fatal("backup flags registered for domain")
+	}
+	if err = cmd.ParseFlags([]string{"-c", "domain.yaml", "--cron", "0 9 * * *"}); err != nil {
+		test.Fatal(err)
+	}
+	if _, err = CronJob(cmd, SourceDomain); err == nil {
+		test.Fatal("notice required")
+	}
+	if err = cmd.ParseFlags([]string{"-n", "SLACK,https://hooks.slack.com/x", "--state", "state.json", "--digest", "9"}); err != nil {
+		test.Fatal(err)
+	}
+	job, err = CronJob(cmd, SourceDomain)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(job.Sources) != 1 || job.Sources[0].File != "domain.yaml" || job.State != "state.json" || *job.DigestHour != 9 {
+		test.Fatal(job)
+	}
+}
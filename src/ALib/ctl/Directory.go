@@ -2,6 +2,7 @@ package ctl
 
 import (
 	"github.com/olekukonko/tablewriter"
+	"io"
 	"os"
 	"path"
 	"runtime"
@@ -53,7 +54,12 @@ func GetWorkspace() (*string, error) {
 
 // PrintTable 输出Table
 func PrintTable(header []string, dataSources [][]string) {
-	table := tablewriter.NewWriter(os.Stdout)
+	WriteTable(os.Stdout, header, dataSources)
+}
+
+// WriteTable 将表格输出到 writer
+func WriteTable(writer io.Writer, header []string, dataSources [][]string) {
+	table := tablewriter.NewWriter(writer)
 	table.SetHeader(header)
 	for _, v := range dataSources {
 		table.Append(v)
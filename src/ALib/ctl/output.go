@@ -0,0 +1,288 @@
+package ctl
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// OutputTable 终端表格 (默认)
+	OutputTable = "table"
+	// OutputJSON JSON 数组 (单条记录时为对象), 字段名为 json 标签
+	OutputJSON = "json"
+	// OutputYAML YAML 列表 (单条记录时为对象), 字段名为 json 标签
+	OutputYAML = "yaml"
+	// OutputCSV CSV, 首行为 json 标签
+	OutputCSV = "csv"
+	// OutputMarkdown Markdown 表格
+	OutputMarkdown = "markdown"
+)
+
+// OutputUsage 输出格式参数说明
+const OutputUsage = "Output Format (table|json|yaml|csv|markdown)"
+
+var outputs = []string{OutputTable, OutputJSON, OutputYAML, OutputCSV, OutputMarkdown}
+
+// ParseOutput 解析输出格式, 为空时为 table
+func ParseOutput(value string) (string, error) {
+	if value == "" {
+		return OutputTable, nil
+	}
+	for _, output := range outputs {
+		if strings.EqualFold(value, output) {
+			return output, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output: %s (%s)", value, strings.Join(outputs, "|"))
+}
+
+// column 行结构体的一个字段
+type column struct {
+	index  int
+	key    string
+	title  string
+	layout string
+}
+
+// Print 按 output 格式输出到标准输出, 见 Write
+func Print(output string, rows interface{}) error {
+	return Write(os.Stdout, output, rows)
+}
+
+// Write 按 output 格式输出 rows; rows 为结构体 (或指针) 的切片, 或单条记录的结构体
+//
+// 字段标签: json 为 json/yaml/csv 中的字段名 ("-" 忽略), title 为表格标题 (默认同字段名),
+// format 为 time.Time 在 table/csv/markdown 中的格式 (默认 2006-01-02 15:04:05);
+// 单条记录在 table/markdown 中按 字段/值 两列输出
+func Write(writer io.Writer, output string, rows interface{}) error {
+	output, err := ParseOutput(output)
+	if err != nil {
+		return err
+	}
+	var value = reflect.Indirect(reflect.ValueOf(rows))
+	var record = value.Kind() == reflect.Struct
+	var rowType reflect.Type
+	switch {
+	case record:
+		rowType = value.Type()
+	case value.Kind() == reflect.Slice:
+		rowType = value.Type().Elem()
+		if rowType.Kind() == reflect.Pointer {
+			rowType = rowType.Elem()
+		}
+	}
+	if rowType == nil || rowType.Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported rows: %T", rows)
+	}
+	var columns = structColumns(rowType)
+	switch output {
+	case OutputJSON:
+		if !record && value.IsNil() {
+			rows = []struct{}{}
+		}
+		var encoder = json.NewEncoder(writer)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case OutputYAML:
+		return writeYAML(writer, value, record, columns)
+	}
+	var items []reflect.Value
+	if record {
+		items = append(items, value)
+	} else {
+		for index := 0; index < value.Len(); index++ {
+			items = append(items, reflect.Indirect(value.Index(index)))
+		}
+	}
+	var header []string
+	var cells [][]string
+	if output == OutputCSV {
+		for _, item := range columns {
+			header = append(header, item.key)
+		}
+	} else {
+		for _, item := range columns {
+			header = append(header, item.title)
+		}
+	}
+	for _, item := range items {
+		var cell []string
+		for _, field := range columns {
+			if item.IsValid() {
+				cell = append(cell, text(item.Field(field.index), field.layout))
+			} else {
+				cell = append(cell, "")
+			}
+		}
+		cells = append(cells, cell)
+	}
+	if record && output != OutputCSV {
+		// 单条记录按 字段/值 输出
+		var fields [][]string
+		for index, title := range header {
+			fields = append(fields, []string{title, cells[0][index]})
+		}
+		header, cells = []string{"字段", "值"}, fields
+	}
+	switch output {
+	case OutputCSV:
+		var csvWriter = csv.NewWriter(writer)
+		_ = csvWriter.Write(header)
+		_ = csvWriter.WriteAll(cells)
+		return csvWriter.Error()
+	case OutputMarkdown:
+		return writeMarkdown(writer, header, cells)
+	default:
+		var table = tablewriter.NewWriter(writer)
+		table.SetHeader(header)
+		table.AppendBulk(cells)
+		table.Render()
+		return nil
+	}
+}
+
+// structColumns 结构体的导出字段
+func structColumns(rowType reflect.Type) []column {
+	var columns []column
+	for index := 0; index < rowType.NumField(); index++ {
+		var field = rowType.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+		var key = strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		var title = field.Tag.Get("title")
+		if title == "" {
+			title = key
+		}
+		columns = append(columns, column{index: index, key: key, title: title, layout: field.Tag.Get("format")})
+	}
+	return columns
+}
+
+// writeYAML 按字段顺序输出 YAML
+func writeYAML(writer io.Writer, value reflect.Value, record bool, columns []column) error {
+	var mapping = func(item reflect.Value) (*yaml.Node, error) {
+		var node = &yaml.Node{Kind: yaml.MappingNode}
+		if !item.IsValid() {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+		}
+		for _, field := range columns {
+			var child yaml.Node
+			err := child.Encode(item.Field(field.index).Interface())
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.key}, &child)
+		}
+		return node, nil
+	}
+	var document *yaml.Node
+	var err error
+	if record {
+		document, err = mapping(value)
+		if err != nil {
+			return err
+		}
+	} else {
+		document = &yaml.Node{Kind: yaml.SequenceNode}
+		for index := 0; index < value.Len(); index++ {
+			node, err := mapping(reflect.Indirect(value.Index(index)))
+			if err != nil {
+				return err
+			}
+			document.Content = append(document.Content, node)
+		}
+		if len(document.Content) == 0 {
+			document.Style = yaml.FlowStyle
+		}
+	}
+	var encoder = yaml.NewEncoder(writer)
+	encoder.SetIndent(2)
+	err = encoder.Encode(document)
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// writeMarkdown 输出 Markdown 表格, 转义单元格中的 | 和换行
+func writeMarkdown(writer io.Writer, header []string, cells [][]string) error {
+	var replacer = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+	var line = func(values []string) string {
+		var escaped []string
+		for _, value := range values {
+			escaped = append(escaped, replacer.Replace(value))
+		}
+		return "| " + strings.Join(escaped, " | ") + " |\n"
+	}
+	var separator []string
+	for range header {
+		separator = append(separator, "---")
+	}
+	var builder strings.Builder
+	builder.WriteString(line(header))
+	builder.WriteString(line(separator))
+	for _, cell := range cells {
+		builder.WriteString(line(cell))
+	}
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// text 字段在 table/csv/markdown 中的文本; 空指针和零值时间为空, 切片以 ", " 连接, map 按 key 排序输出 key=value
+func text(value reflect.Value, layout string) string {
+	if value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		return text(value.Elem(), layout)
+	}
+	if value.CanInterface() {
+		switch item := value.Interface().(type) {
+		case time.Time:
+			if item.IsZero() {
+				return ""
+			}
+			if layout == "" {
+				layout = "2006-01-02 15:04:05"
+			}
+			return item.Format(layout)
+		case fmt.Stringer:
+			return item.String()
+		case []byte:
+			return fmt.Sprintf("%X", item)
+		}
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		var values []string
+		for index := 0; index < value.Len(); index++ {
+			values = append(values, text(value.Index(index), layout))
+		}
+		return strings.Join(values, ", ")
+	case reflect.Map:
+		var values []string
+		for _, key := range value.MapKeys() {
+			values = append(values, text(key, layout)+"="+text(value.MapIndex(key), layout))
+		}
+		sort.Strings(values)
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,85 @@
+package ctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+	"time"
+)
+
+type outputRow struct {
+	Name    string     `json:"name" title:"名称"`
+	Count   int        `json:"count" title:"数量"`
+	Expires *time.Time `json:"expires" title:"过期日期" format:"2006-01-02"`
+	Tags    []string   `json:"tags" title:"标签"`
+	secret  string
+}
+
+func TestWrite(test *testing.T) {
+	var expires = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	var rows = []outputRow{
+		{Name: "a|b", Count: 2, Expires: &expires, Tags: []string{"x", "y"}, secret: "hidden"},
+		{Name: "c", Count: 0},
+	}
+	var expected = map[string]string{
+		OutputCSV:      "name,count,expires,tags\na|b,2,2024-05-01,\"x, y\"\nc,0,,\n",
+		OutputMarkdown: "| 名称 | 数量 | 过期日期 | 标签 |\n| --- | --- | --- | --- |\n| a\\|b | 2 | 2024-05-01 | x, y |\n| c | 0 |  |  |\n",
+	}
+	for output, text := range expected {
+		var buffer bytes.Buffer
+		if err := Write(&buffer, output, rows); err != nil {
+			test.Fatal(err)
+		}
+		if buffer.String() != text {
+			test.Fatal(output, buffer.String())
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := Write(&buffer, "JSON", rows); err != nil {
+		test.Fatal(err)
+	}
+	var values []map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &values); err != nil {
+		test.Fatal(err)
+	}
+	if len(values) != 2 || values[0]["count"] != float64(2) || values[0]["expires"] != "2024-05-01T08:00:00Z" || values[1]["expires"] != nil || values[0]["secret"] != nil {
+		test.Fatal(values)
+	}
+
+	buffer.Reset()
+	if err := Write(&buffer, OutputYAML, rows); err != nil {
+		test.Fatal(err)
+	}
+	if !strings.HasPrefix(buffer.String(), "- name: a|b\n  count: 2\n") {
+		test.Fatal(buffer.String())
+	}
+	var items []outputRow
+	if err := yaml.Unmarshal(buffer.Bytes(), &items); err != nil || len(items) != 2 || items[0].Tags[1] != "y" {
+		test.Fatal(items, err)
+	}
+
+	// 没有行时输出空列表
+	buffer.Reset()
+	if err := Write(&buffer, OutputJSON, []outputRow(nil)); err != nil || buffer.String() != "[]\n" {
+		test.Fatal(buffer.String(), err)
+	}
+
+	// 单条记录按 字段/值 输出
+	buffer.Reset()
+	if err := Write(&buffer, OutputMarkdown, &rows[1]); err != nil {
+		test.Fatal(err)
+	}
+	if buffer.String() != "| 字段 | 值 |\n| --- | --- |\n| 名称 | c |\n| 数量 | 0 |\n| 过期日期 |  |\n| 标签 |  |\n" {
+		test.Fatal(buffer.String())
+	}
+
+	if err := Write(&buffer, "xml", rows); err == nil {
+		test.Fatal("output error expected")
+	}
+	if err := Write(&buffer, OutputJSON, []string{"a"}); err == nil {
+		test.Fatal("rows error expected")
+	}
+}
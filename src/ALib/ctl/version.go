@@ -0,0 +1,4 @@
+package ctl
+
+// Version 程序版本号
+const Version = "1.0.2"
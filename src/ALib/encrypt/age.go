@@ -0,0 +1,202 @@
+package encrypt
+
+import (
+	"bufio"
+	"errors"
+	"filippo.io/age"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Ext 加密文件后缀
+const Ext = ".age"
+
+// passphrasePrefix 口令前缀; 如 pass:123456, 口令也可以读取环境变量 (pass:env:NAME) 或文件 (pass:file:PATH)
+const passphrasePrefix = "pass:"
+
+// 口令来源前缀
+const (
+	passphraseEnvPrefix  = "env:"
+	passphraseFilePrefix = "file:"
+)
+
+// ErrWrongKey 密钥或口令不匹配
+var ErrWrongKey = errors.New("decrypt failed: wrong key or passphrase")
+
+// ParseRecipients 解析加密接收者; 支持 age 公钥 (age1...)、口令 (pass:xxx, 见 passphrase) 和公钥文件路径 (每行一个公钥)
+func ParseRecipients(values []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			continue
+		case strings.HasPrefix(value, passphrasePrefix):
+			if len(values) > 1 {
+				return nil, fmt.Errorf("encrypt recipient: passphrase can not be combined with other recipients")
+			}
+			password, err := passphrase(value)
+			if err != nil {
+				return nil, fmt.Errorf("encrypt recipient: %w", err)
+			}
+			recipient, err := age.NewScryptRecipient(password)
+			if err != nil {
+				return nil, fmt.Errorf("encrypt recipient: %w", err)
+			}
+			recipients = append(recipients, recipient)
+		case strings.HasPrefix(value, "age1"):
+			recipient, err := age.ParseX25519Recipient(value)
+			if err != nil {
+				return nil, fmt.Errorf("encrypt recipient: %w", err)
+			}
+			recipients = append(recipients, recipient)
+		default:
+			file, err := os.Open(value)
+			if err != nil {
+				return nil, fmt.Errorf("encrypt recipient: %w", err)
+			}
+			items, err := age.ParseRecipients(file)
+			_ = file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("encrypt recipient %s: %w", value, err)
+			}
+			recipients = append(recipients, items...)
+		}
+	}
+	return recipients, nil
+}
+
+// ParseIdentities 解析解密密钥; 支持 age 私钥 (AGE-SECRET-KEY-1...)、口令 (pass:xxx, 见 passphrase) 和私钥文件路径
+func ParseIdentities(values []string) ([]age.Identity, error) {
+	var identities []age.Identity
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			continue
+		case strings.HasPrefix(value, passphrasePrefix):
+			password, err := passphrase(value)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt identity: %w", err)
+			}
+			identity, err := age.NewScryptIdentity(password)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt identity: %w", err)
+			}
+			identities = append(identities, identity)
+		case strings.HasPrefix(value, "AGE-SECRET-KEY-1"):
+			identity, err := age.ParseX25519Identity(value)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt identity: %w", err)
+			}
+			identities = append(identities, identity)
+		default:
+			file, err := os.Open(value)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt identity: %w", err)
+			}
+			items, err := age.ParseIdentities(bufio.NewReader(file))
+			_ = file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("decrypt identity %s: %w", value, err)
+			}
+			identities = append(identities, items...)
+		}
+	}
+	if len(identities) <= 0 {
+		return nil, fmt.Errorf("decrypt identity required")
+	}
+	return identities, nil
+}
+
+// passphrase 解析口令; pass:env:NAME 读取环境变量, pass:file:PATH 读取文件内容 (去掉首尾空白), 其它为口令本身.
+// 命令行参数中的口令会出现在进程列表中, 应使用环境变量或文件
+func passphrase(value string) (string, error) {
+	var password = strings.TrimPrefix(value, passphrasePrefix)
+	switch {
+	case strings.HasPrefix(password, passphraseEnvPrefix):
+		var name = strings.TrimPrefix(password, passphraseEnvPrefix)
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("passphrase environment variable not set: %s", name)
+		}
+		password = env
+	case strings.HasPrefix(password, passphraseFilePrefix):
+		fileBytes, err := os.ReadFile(strings.TrimPrefix(password, passphraseFilePrefix))
+		if err != nil {
+			return "", fmt.Errorf("passphrase: %w", err)
+		}
+		password = strings.TrimSpace(string(fileBytes))
+	}
+	if password == "" {
+		return "", fmt.Errorf("passphrase required")
+	}
+	return password, nil
+}
+
+// Writer 加密写入 w; recipients 为空时不加密. 必须调用 Close 写入结尾 (不关闭 w)
+func Writer(w io.Writer, recipients []string) (io.WriteCloser, error) {
+	items, err := ParseRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) <= 0 {
+		return nopCloser{w}, nil
+	}
+	return age.Encrypt(w, items...)
+}
+
+// Reader 解密读取 r; 密钥不匹配时返回 ErrWrongKey
+func Reader(r io.Reader, identities []string) (io.Reader, error) {
+	items, err := ParseIdentities(identities)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := age.Decrypt(r, items...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, ErrWrongKey
+		}
+		return nil, fmt.Errorf("decrypt failed: %w", err)
+	}
+	return reader, nil
+}
+
+// DecryptFile 解密文件; src 加密文件, target 输出文件
+func DecryptFile(src, target string, identities []string) error {
+	fr, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = fr.Close()
+	}()
+	reader, err := Reader(fr, identities)
+	if err != nil {
+		return err
+	}
+	fw, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, reader)
+	if closeErr := fw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return fmt.Errorf("decrypt failed: %w", err)
+	}
+	return nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
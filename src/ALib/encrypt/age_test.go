@@ -0,0 +1,84 @@
+package encrypt
+
+import (
+	"errors"
+	"filippo.io/age"
+	"github.com/longyuan/lib.v3/compress"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchive(test *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		test.Fatal(err)
+	}
+	var src = test.TempDir()
+	err = os.WriteFile(filepath.Join(src, "secret.yaml"), []byte("password: 123456"), 0600)
+	if err != nil {
+		test.Fatal(err)
+	}
+	var cases = []struct {
+		recipient string
+		identity  string
+		wrong     string
+		formats   []compress.Format
+	}{
+		{identity.Recipient().String(), identity.String(), "pass:123456", compress.Formats},
+		// scrypt 较慢, 只测试一种格式
+		{"pass:correct horse", "pass:correct horse", "pass:wrong", []compress.Format{compress.FormatTarGz}},
+	}
+	for _, item := range cases {
+		for _, format := range item.formats {
+			var target = filepath.Join(test.TempDir(), "backup"+format.Ext())
+			output, err := Archive(src, target, format, []string{item.recipient}, false)
+			if err != nil {
+				test.Fatal(err)
+			}
+			if *output != target+Ext {
+				test.Fatal(*output)
+			}
+			err = Unzip(*output, test.TempDir(), []string{item.wrong})
+			if !errors.Is(err, ErrWrongKey) {
+				test.Fatal("wrong key:", err)
+			}
+			var out = test.TempDir()
+			err = Unzip(*output, out, []string{item.identity})
+			if err != nil {
+				test.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(out, "secret.yaml"))
+			if err != nil || string(data) != "password: 123456" {
+				test.Fatal(string(data), err)
+			}
+		}
+	}
+}
+
+func TestPassphrase(test *testing.T) {
+	test.Setenv("ARCHIVE_PASSPHRASE", "correct horse")
+	var passphraseFile = filepath.Join(test.TempDir(), "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte("battery staple\n"), 0600); err != nil {
+		test.Fatal(err)
+	}
+	var cases = map[string]string{
+		"pass:123456":                 "123456",
+		"pass:env:ARCHIVE_PASSPHRASE": "correct horse",
+		"pass:file:" + passphraseFile: "battery staple",
+	}
+	for value, expected := range cases {
+		password, err := passphrase(value)
+		if err != nil || password != expected {
+			test.Fatal(value, password, err)
+		}
+	}
+	for _, value := range []string{"pass:env:MISSING_ARCHIVE_PASSPHRASE", "pass:file:" + passphraseFile + ".missing", "pass:"} {
+		if _, err := passphrase(value); err == nil {
+			test.Fatal("error expected:", value)
+		}
+	}
+	if _, err := ParseRecipients([]string{"pass:env:MISSING_ARCHIVE_PASSPHRASE"}); err == nil {
+		test.Fatal("recipient error expected")
+	}
+}
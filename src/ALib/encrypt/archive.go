@@ -0,0 +1,92 @@
+package encrypt
+
+import (
+	"github.com/longyuan/lib.v3/compress"
+	"io"
+	"os"
+	"strings"
+)
+
+// Encrypted 是否配置了加密接收者
+func Encrypted(recipients []string) bool {
+	for _, item := range recipients {
+		if strings.TrimSpace(item) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Archive 压缩目录并加密写入文件, 明文归档不落盘; recipients 为空时不加密.
+// 返回实际输出文件 (加密时追加 .age 后缀)
+func Archive(src, target string, format compress.Format, recipients []string, delete bool) (*string, error) {
+	if !Encrypted(recipients) {
+		err := compress.Compress(src, target, format, delete)
+		if err != nil {
+			return nil, err
+		}
+		return &target, nil
+	}
+	if !strings.HasSuffix(target, Ext) {
+		target += Ext
+	}
+	fw, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = archive(src, fw, format, recipients)
+	if closeErr := fw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return nil, err
+	}
+	if delete {
+		err = os.RemoveAll(src)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &target, nil
+}
+
+// Stream 压缩目录并加密写入 w; recipients 为空时不加密
+func Stream(src string, w io.Writer, format compress.Format, recipients []string) error {
+	return archive(src, w, format, recipients)
+}
+
+func archive(src string, w io.Writer, format compress.Format, recipients []string) error {
+	ew, err := Writer(w, recipients)
+	if err != nil {
+		return err
+	}
+	err = compress.Archive(src, ew, format)
+	if closeErr := ew.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Unzip 解压归档文件, .age 后缀的文件先解密 (tar 格式解密内容不落盘); target 输出目录
+func Unzip(src, target string, identities []string) error {
+	if !strings.HasSuffix(src, Ext) {
+		return compress.Unzip(src, target)
+	}
+	format, err := compress.DetectFormat(strings.TrimSuffix(src, Ext))
+	if err != nil {
+		return err
+	}
+	fr, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = fr.Close()
+	}()
+	reader, err := Reader(fr, identities)
+	if err != nil {
+		return err
+	}
+	return compress.ExtractReader(reader, format, target)
+}
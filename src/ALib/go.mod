@@ -3,13 +3,21 @@ module github.com/longyuan/lib.v3
 go 1.19
 
 require (
+	filippo.io/age v1.1.1
 	github.com/fatih/color v1.15.0
+	github.com/klauspost/compress v1.16.7
 	github.com/olekukonko/tablewriter v0.0.5
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/spf13/cobra v1.7.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.17 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/crypto v0.4.0 // indirect
 	golang.org/x/sys v0.6.0 // indirect
 )
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// AddFlags 为根命令添加日志参数 (--log-level, --log-format, --log-file, --log-max-size, --log-max-backups),
+// 运行子命令前按参数初始化日志; tool 为所有日志的 tool 字段
+func AddFlags(rootCmd *cobra.Command, tool string) {
+	var flags = rootCmd.PersistentFlags()
+	flags.String("log-level", "info", "Log Level (debug|info|warn|error)")
+	flags.String("log-format", FormatText, "Log Format (text|json)")
+	flags.String("log-file", "", "Also Write Logs To File, Rotated By Size")
+	flags.Int("log-max-size", 100, "Log File Size (MB) Before Rotation")
+	flags.Int("log-max-backups", defaultMaxBackups, "Rotated Log Files To Keep")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		level, err := cmd.Flags().GetString("log-level")
+		if err != nil {
+			return err
+		}
+		format, err := cmd.Flags().GetString("log-format")
+		if err != nil {
+			return err
+		}
+		file, err := cmd.Flags().GetString("log-file")
+		if err != nil {
+			return err
+		}
+		maxSize, err := cmd.Flags().GetInt("log-max-size")
+		if err != nil {
+			return err
+		}
+		maxBackups, err := cmd.Flags().GetInt("log-max-backups")
+		if err != nil {
+			return err
+		}
+		return Setup(Options{
+			Level:      level,
+			Format:     format,
+			File:       file,
+			MaxSize:    maxSize,
+			MaxBackups: maxBackups,
+			Fields:     Fields{"tool": tool},
+		})
+	}
+}
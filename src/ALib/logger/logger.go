@@ -0,0 +1,288 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level 日志级别
+type Level int
+
+const (
+	// LevelDebug 调试
+	LevelDebug Level = iota
+	// LevelInfo 信息 (默认)
+	LevelInfo
+	// LevelWarn 警告
+	LevelWarn
+	// LevelError 错误
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// ParseLevel 解析日志级别 (debug|info|warn|error), 为空时为 info
+func ParseLevel(value string) (Level, error) {
+	if value == "" {
+		return LevelInfo, nil
+	}
+	for level, name := range levelNames {
+		if strings.EqualFold(value, name) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unsupported log level: %s (debug|info|warn|error)", value)
+}
+
+func (level Level) String() string {
+	return levelNames[level]
+}
+
+const (
+	// FormatText 终端输出, 按级别着色; 字段以 key=value 追加在消息后
+	FormatText = "text"
+	// FormatJSON 每行一个 JSON 对象 (time, level, msg 和字段), 用于日志采集
+	FormatJSON = "json"
+)
+
+// Fields 日志字段; 如 tool, job, host, namespace
+type Fields map[string]string
+
+// Options 日志配置
+type Options struct {
+	// Level 最低输出级别 (debug|info|warn|error), 默认 info
+	Level string
+	// Format 输出格式 (text|json), 默认 text
+	Format string
+	// File 同时写入的日志文件, 为空时只输出到终端; 文件中不包含颜色, text 格式时每行包含时间和级别
+	File string
+	// MaxSize 日志文件轮转大小 (MB), 默认 100
+	MaxSize int
+	// MaxBackups 保留的轮转文件数量 (file.1 ~ file.N), 默认 5
+	MaxBackups int
+	// Fields 所有日志的字段 (如 tool); text 格式的终端输出中省略
+	Fields Fields
+}
+
+// output 日志输出配置, 由 Setup 修改
+var output = struct {
+	lock   sync.Mutex
+	level  Level
+	format string
+	file   io.WriteCloser
+	fields Fields
+	redact func(text string) string
+}{level: LevelInfo, format: FormatText, redact: func(text string) string { return text }}
+
+// SetRedact 设置写入前替换日志中敏感内容的函数; secret 登记密钥时设置为 secret.Redact
+func SetRedact(redact func(text string) string) {
+	output.lock.Lock()
+	defer output.lock.Unlock()
+	output.redact = redact
+}
+
+// Setup 按配置初始化日志; 重复调用时关闭之前的日志文件
+func Setup(options Options) error {
+	level, err := ParseLevel(options.Level)
+	if err != nil {
+		return err
+	}
+	var format = strings.ToLower(options.Format)
+	if format == "" {
+		format = FormatText
+	}
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unsupported log format: %s (text|json)", options.Format)
+	}
+	var file io.WriteCloser
+	if options.File != "" {
+		file, err = openRotateFile(options.File, int64(options.MaxSize)*1024*1024, options.MaxBackups)
+		if err != nil {
+			return err
+		}
+	}
+	output.lock.Lock()
+	defer output.lock.Unlock()
+	if output.file != nil {
+		_ = output.file.Close()
+	}
+	output.level = level
+	output.format = format
+	output.file = file
+	output.fields = options.Fields
+	return nil
+}
+
+// Logger 带字段的日志
+type Logger struct {
+	fields Fields
+}
+
+// defaultLogger 没有字段的日志
+var defaultLogger = &Logger{}
+
+// With 创建带字段的日志
+func With(fields Fields) *Logger {
+	return defaultLogger.With(fields)
+}
+
+// With 创建包含当前字段和 fields 的日志, fields 中的字段覆盖当前字段; 忽略值为空的字段
+func (logger *Logger) With(fields Fields) *Logger {
+	var values = Fields{}
+	for key, value := range logger.fields {
+		values[key] = value
+	}
+	for key, value := range fields {
+		if value != "" {
+			values[key] = value
+		}
+	}
+	return &Logger{fields: values}
+}
+
+type contextKey struct{}
+
+// NewContext 返回携带 logger 的 ctx
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext ctx 携带的日志 (如调度任务的 job 字段), 没有时返回没有字段的日志
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*Logger); ok {
+			return logger
+		}
+	}
+	return defaultLogger
+}
+
+// Debug 调试日志
+func (logger *Logger) Debug(format string, args ...interface{}) {
+	logger.write(LevelDebug, color.FgHiBlack, format, args)
+}
+
+// Info 信息日志 (蓝色)
+func (logger *Logger) Info(format string, args ...interface{}) {
+	logger.write(LevelInfo, color.FgBlue, format, args)
+}
+
+// Success 成功日志, 级别同 Info (绿色)
+func (logger *Logger) Success(format string, args ...interface{}) {
+	logger.write(LevelInfo, color.FgGreen, format, args)
+}
+
+// Warn 警告日志 (黄色)
+func (logger *Logger) Warn(format string, args ...interface{}) {
+	logger.write(LevelWarn, color.FgYellow, format, args)
+}
+
+// Error 错误日志 (红色); 只输出, 不退出进程
+func (logger *Logger) Error(format string, args ...interface{}) {
+	logger.write(LevelError, color.FgRed, format, args)
+}
+
+// Debug 调试日志
+func Debug(format string, args ...interface{}) {
+	defaultLogger.write(LevelDebug, color.FgHiBlack, format, args)
+}
+
+// Info 信息日志 (蓝色)
+func Info(format string, args ...interface{}) {
+	defaultLogger.write(LevelInfo, color.FgBlue, format, args)
+}
+
+// Success 成功日志, 级别同 Info (绿色)
+func Success(format string, args ...interface{}) {
+	defaultLogger.write(LevelInfo, color.FgGreen, format, args)
+}
+
+// Warn 警告日志 (黄色)
+func Warn(format string, args ...interface{}) {
+	defaultLogger.write(LevelWarn, color.FgYellow, format, args)
+}
+
+// Error 错误日志 (红色); 只输出, 不退出进程
+func Error(format string, args ...interface{}) {
+	defaultLogger.write(LevelError, color.FgRed, format, args)
+}
+
+// write 输出一条日志到标准错误 (标准输出只用于命令结果, 见 ctl.Print); 消息和字段中的敏感内容会被替换 (见 SetRedact)
+func (logger *Logger) write(level Level, attribute color.Attribute, format string, args []interface{}) {
+	output.lock.Lock()
+	defer output.lock.Unlock()
+	if level < output.level {
+		return
+	}
+	var message = format
+	if len(args) > 0 {
+		message = fmt.Sprintf(format, args...)
+	}
+	message = strings.TrimSuffix(message, "\n")
+	var now = time.Now()
+	if output.format == FormatJSON {
+		var line = output.redact(jsonLine(now, level, message, output.fields, logger.fields))
+		_, _ = io.WriteString(color.Error, line)
+		if output.file != nil {
+			_, _ = io.WriteString(output.file, line)
+		}
+		return
+	}
+	_, _ = color.New(attribute).Fprintln(color.Error, output.redact(message+textFields(logger.fields)))
+	if output.file != nil {
+		var line = now.Format("2006-01-02 15:04:05") + " " + strings.ToUpper(level.String()) + " " + message + textFields(output.fields, logger.fields)
+		_, _ = io.WriteString(output.file, output.redact(line)+"\n")
+	}
+}
+
+// jsonLine JSON 格式的一行日志
+func jsonLine(now time.Time, level Level, message string, fields ...Fields) string {
+	var values = map[string]string{}
+	for _, item := range fields {
+		for key, value := range item {
+			values[key] = value
+		}
+	}
+	values["time"] = now.Format(time.RFC3339Nano)
+	values["level"] = level.String()
+	values["msg"] = message
+	// map 按 key 排序输出
+	data, _ := json.Marshal(values)
+	return string(data) + "\n"
+}
+
+// textFields 按 key 排序的 key=value, 包含空格等字符的值加引号
+func textFields(fields ...Fields) string {
+	var values = map[string]string{}
+	for _, item := range fields {
+		for key, value := range item {
+			values[key] = value
+		}
+	}
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var builder strings.Builder
+	for _, key := range keys {
+		var value = values[key]
+		if value == "" || strings.ContainsAny(value, " \t\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		builder.WriteString(" " + key + "=" + value)
+	}
+	return builder.String()
+}
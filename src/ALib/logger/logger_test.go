@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func capture(test *testing.T) *bytes.Buffer {
+	var buffer bytes.Buffer
+	var output, noColor = color.Error, color.NoColor
+	color.Error, color.NoColor = &buffer, true
+	test.Cleanup(func() {
+		color.Error, color.NoColor = output, noColor
+		_ = Setup(Options{})
+	})
+	return &buffer
+}
+
+func TestJSON(test *testing.T) {
+	var buffer = capture(test)
+	err := Setup(Options{Level: "warn", Format: "JSON", Fields: Fields{"tool": "nacos"}})
+	if err != nil {
+		test.Fatal(err)
+	}
+	var ctx = NewContext(context.Background(), With(Fields{"job": "nacos-prod"}))
+	FromContext(ctx).Info("skipped")
+	FromContext(ctx).With(Fields{"host": "127.0.0.1"}).Warn("retry %d", 2)
+	Error("failed")
+	var lines = strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		test.Fatal(buffer.String())
+	}
+	var entry map[string]string
+	if err = json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		test.Fatal(err)
+	}
+	if entry["level"] != "warn" || entry["msg"] != "retry 2" || entry["tool"] != "nacos" || entry["job"] != "nacos-prod" || entry["host"] != "127.0.0.1" || entry["time"] == "" {
+		test.Fatal(entry)
+	}
+	if err = Setup(Options{Level: "trace"}); err == nil {
+		test.Fatal("level error expected")
+	}
+	if err = Setup(Options{Format: "xml"}); err == nil {
+		test.Fatal("format error expected")
+	}
+}
+
+func TestText(test *testing.T) {
+	var buffer = capture(test)
+	var logPath = filepath.Join(test.TempDir(), "logs", "nctl.log")
+	err := Setup(Options{File: logPath, Fields: Fields{"tool": "nacos"}})
+	if err != nil {
+		test.Fatal(err)
+	}
+	Debug("hidden")
+	With(Fields{"namespace": "dev ops"}).Success("100% done")
+	if buffer.String() != "100% done namespace=\"dev ops\"\n" {
+		test.Fatal(buffer.String())
+	}
+	fileBytes, err := os.ReadFile(logPath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if !strings.HasSuffix(string(fileBytes), " INFO 100% done namespace=\"dev ops\" tool=nacos\n") {
+		test.Fatal(string(fileBytes))
+	}
+}
+
+func TestRotate(test *testing.T) {
+	var logPath = filepath.Join(test.TempDir(), "app.log")
+	writer, err := openRotateFile(logPath, 10, 2)
+	if err != nil {
+		test.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err = writer.Write([]byte(line)); err != nil {
+			test.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		test.Fatal(err)
+	}
+	for path, expected := range map[string]string{logPath: "fourth\n", logPath + ".1": "third\n", logPath + ".2": "second\n"} {
+		fileBytes, err := os.ReadFile(path)
+		if err != nil || string(fileBytes) != expected {
+			test.Fatal(path, string(fileBytes), err)
+		}
+	}
+	if _, err = os.Stat(logPath + ".3"); !os.IsNotExist(err) {
+		test.Fatal("only 2 backups expected")
+	}
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// defaultMaxSize 默认日志文件轮转大小
+	defaultMaxSize = 100 * 1024 * 1024
+	// defaultMaxBackups 默认保留的轮转文件数量
+	defaultMaxBackups = 5
+)
+
+// rotateFile 按大小轮转的日志文件; 超过 maxSize 时 file 重命名为 file.1, 原 file.1 重命名为 file.2, 依次类推, 超过 maxBackups 的删除
+type rotateFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	lock       sync.Mutex
+	file       *os.File
+	size       int64
+}
+
+// openRotateFile 打开 (追加) 日志文件, 不存在时创建文件和目录; maxSize, maxBackups 小于等于 0 时使用默认值
+func openRotateFile(path string, maxSize int64, maxBackups int) (*rotateFile, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+	var writer = rotateFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	err := writer.open()
+	if err != nil {
+		return nil, err
+	}
+	return &writer, nil
+}
+
+func (writer *rotateFile) open() error {
+	err := os.MkdirAll(filepath.Dir(writer.path), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(writer.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	writer.file = file
+	writer.size = info.Size()
+	return nil
+}
+
+// Write 写入日志; 写入后超过 maxSize 时先轮转 (单次写入不会拆分到两个文件)
+func (writer *rotateFile) Write(p []byte) (int, error) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	if writer.file == nil {
+		return 0, os.ErrClosed
+	}
+	if writer.size > 0 && writer.size+int64(len(p)) > writer.maxSize {
+		err := writer.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := writer.file.Write(p)
+	writer.size += int64(n)
+	return n, err
+}
+
+// rotate 轮转日志文件并打开新文件; 重命名失败时继续写入原文件
+func (writer *rotateFile) rotate() error {
+	_ = writer.file.Close()
+	writer.file = nil
+	_ = os.Remove(fmt.Sprintf("%s.%d", writer.path, writer.maxBackups))
+	for index := writer.maxBackups - 1; index >= 1; index-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", writer.path, index), fmt.Sprintf("%s.%d", writer.path, index+1))
+	}
+	_ = os.Rename(writer.path, writer.path+".1")
+	return writer.open()
+}
+
+// Close 关闭日志文件
+func (writer *rotateFile) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	if writer.file == nil {
+		return nil
+	}
+	err := writer.file.Close()
+	writer.file = nil
+	return err
+}
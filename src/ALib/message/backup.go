@@ -0,0 +1,18 @@
+package message
+
+// BackupEvent 备份结果事件; source 来源工具, name 备份配置名称, cloudPath 上传路径, err 备份异常
+func BackupEvent(source, name, cloudPath string, err error) Event {
+	var event = NewEvent(source, name, "Backup", LevelInfo, err)
+	event.Message = cloudPath
+	event.Labels = map[string]string{"config": name}
+	return event
+}
+
+// ReplicaBackupEvent 多目标备份结果事件; message 每个目标的结果, degraded 部分目标失败但满足成功策略时为 LevelWarning
+func ReplicaBackupEvent(source, name, message string, degraded bool, err error) Event {
+	var event = BackupEvent(source, name, message, err)
+	if err == nil && degraded {
+		event.Level = LevelWarning
+	}
+	return event
+}
@@ -0,0 +1,250 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// retryAttempts 单个分片最大重试次数, retryDelay 首次重试等待时间 (指数退避)
+var retryAttempts = 3
+var retryDelay = time.Second
+
+// Report 推送结果
+type Report struct {
+	// Channel 渠道名称
+	Channel string
+	// Chunks 分片总数
+	Chunks int
+	// Failed 推送失败的分片
+	Failed []ChunkFailure
+}
+
+// ChunkFailure 推送失败的分片
+type ChunkFailure struct {
+	// Index 分片序号, 从 0 开始
+	Index int
+	// Bytes 分片大小
+	Bytes int
+	// Attempts 尝试次数
+	Attempts int
+	// Error 最后一次异常
+	Error string
+}
+
+// Err 存在推送失败的分片时返回异常
+func (report *Report) Err() error {
+	if report == nil || len(report.Failed) <= 0 {
+		return nil
+	}
+	var failed []string
+	for _, item := range report.Failed {
+		failed = append(failed, fmt.Sprintf("#%d: %s", item.Index, item.Error))
+	}
+	return fmt.Errorf("message push %s failed (%d/%d): %s",
+		report.Channel, len(report.Failed), report.Chunks, strings.Join(failed, "; "))
+}
+
+// deliveryError 推送异常; retry 是否可重试 (5xx、限流), after 服务端要求的等待时间
+type deliveryError struct {
+	err   error
+	retry bool
+	after time.Duration
+}
+
+func (e *deliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+// retryable 包装可重试异常
+func retryable(err error) error {
+	return &deliveryError{err: err, retry: true}
+}
+
+// isRetryable 是否可重试; 网络异常可重试
+func isRetryable(err error) (bool, time.Duration) {
+	var value *deliveryError
+	if errors.As(err, &value) {
+		return value.retry, value.after
+	}
+	return true, 0
+}
+
+// deliver 按限流配额逐个推送分片, 失败时指数退避重试; key 限流标识 (如 Webhook 地址), quota 每分钟最大推送次数
+func deliver(channel, key string, quota int, chunks []string, send func(chunk string) error) *Report {
+	var report = &Report{Channel: channel, Chunks: len(chunks)}
+	for index, chunk := range chunks {
+		var attempts int
+		var err error
+		for attempts < retryAttempts {
+			attempts++
+			limiter.Wait(channel+":"+key, quota)
+			err = send(chunk)
+			if err == nil {
+				break
+			}
+			retry, after := isRetryable(err)
+			if !retry || attempts >= retryAttempts {
+				break
+			}
+			var delay = retryDelay * time.Duration(1<<(attempts-1))
+			if after > delay {
+				delay = after
+			}
+			time.Sleep(delay)
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, ChunkFailure{
+				Index: index, Bytes: len(chunk), Attempts: attempts, Error: err.Error(),
+			})
+		}
+	}
+	return report
+}
+
+// rateLimiter 每分钟推送次数限制 (滑动窗口), 所有渠道共享
+type rateLimiter struct {
+	lock sync.Mutex
+	sent map[string][]time.Time
+}
+
+var limiter = &rateLimiter{sent: map[string][]time.Time{}}
+
+// Wait 等待直到 key 在最近一分钟内的推送次数小于 perMinute
+func (limiter *rateLimiter) Wait(key string, perMinute int) {
+	if perMinute <= 0 {
+		return
+	}
+	for {
+		limiter.lock.Lock()
+		var now = time.Now()
+		var window []time.Time
+		for _, item := range limiter.sent[key] {
+			if now.Sub(item) < time.Minute {
+				window = append(window, item)
+			}
+		}
+		if len(window) < perMinute {
+			limiter.sent[key] = append(window, now)
+			limiter.lock.Unlock()
+			return
+		}
+		limiter.sent[key] = window
+		var wait = window[0].Add(time.Minute).Sub(now)
+		limiter.lock.Unlock()
+		time.Sleep(wait)
+	}
+}
+
+// splitBytes 按字节数拆分消息, 优先按行拆分, 超长行按 UTF-8 字符边界拆分
+func splitBytes(content string, limit int) []string {
+	var chunks []string
+	var current strings.Builder
+	var flush = func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+	for _, row := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		for len(row) > limit {
+			flush()
+			var cut = limit
+			for cut > 0 && !utf8.RuneStart(row[cut]) {
+				cut--
+			}
+			chunks = append(chunks, row[:cut])
+			row = row[cut:]
+		}
+		if current.Len() > 0 && current.Len()+1+len(row) > limit {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteByte('\n')
+		}
+		current.WriteString(row)
+	}
+	flush()
+	return chunks
+}
+
+// postJSON 发送 JSON 请求; check 校验响应内容, 为空时只校验状态码
+func postJSON(url string, body any, check func(body []byte) error) error {
+	requestByteData, err := json.Marshal(body)
+	if err != nil {
+		return &deliveryError{err: err}
+	}
+	return send("POST", url, map[string]string{"Content-Type": "application/json"}, requestByteData, check)
+}
+
+// send 发送 HTTP 请求; 429、5xx 响应为可重试异常, 其他非 2xx 响应为不可重试异常
+func send(method, url string, headers map[string]string, body []byte, check func(body []byte) error) error {
+	request, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return &deliveryError{err: err}
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500 {
+		var after time.Duration
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+			after = time.Duration(seconds) * time.Second
+		}
+		return &deliveryError{err: fmt.Errorf("%s %s", response.Status, strings.TrimSpace(string(responseBody))), retry: true, after: after}
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &deliveryError{err: fmt.Errorf("%s %s", response.Status, strings.TrimSpace(string(responseBody)))}
+	}
+	if check != nil {
+		return check(responseBody)
+	}
+	return nil
+}
+
+// checkErrCode 校验 {"errcode": 0, "errmsg": "ok"} 格式的响应 (企业微信、钉钉); rateLimitCodes 限流错误码
+func checkErrCode(rateLimitCodes ...int) func(body []byte) error {
+	return func(body []byte) error {
+		var result = struct {
+			ErrCode *int   `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+		}{}
+		err := json.Unmarshal(body, &result)
+		if err != nil {
+			return &deliveryError{err: fmt.Errorf("response error: %s", strings.TrimSpace(string(body)))}
+		}
+		if result.ErrCode == nil || *result.ErrCode == 0 {
+			return nil
+		}
+		err = fmt.Errorf("errcode %d: %s", *result.ErrCode, result.ErrMsg)
+		for _, code := range rateLimitCodes {
+			if code == *result.ErrCode {
+				return retryable(err)
+			}
+		}
+		return &deliveryError{err: err}
+	}
+}
@@ -0,0 +1,58 @@
+package message
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSplitBytes(test *testing.T) {
+	var content = strings.Repeat("> x.com **SSL ( 剩余: 30天 )**\n", 300) + strings.Repeat("域", 2000)
+	var chunks = splitBytes(content, weChatMarkdownBytes)
+	for i := range chunks {
+		chunks[i] = strings.ReplaceAll(chunks[i], "\n", "")
+	}
+	if strings.Join(chunks, "") != strings.ReplaceAll(content, "\n", "") {
+		test.Fatal("chunks lost content")
+	}
+	for _, chunk := range splitBytes(content, weChatMarkdownBytes) {
+		if len(chunk) > weChatMarkdownBytes || !utf8.ValidString(chunk) {
+			test.Fatalf("invalid chunk: %d bytes", len(chunk))
+		}
+	}
+}
+
+func TestDeliverRetry(test *testing.T) {
+	var delay = retryDelay
+	test.Cleanup(func() {
+		retryDelay = delay
+	})
+	retryDelay = time.Millisecond
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case strings.Contains(r.URL.Path, "busy") && requests == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case strings.Contains(r.URL.Path, "busy"):
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		default:
+			_, _ = w.Write([]byte(`{"errcode":93000,"errmsg":"invalid webhook url"}`))
+		}
+	}))
+	defer server.Close()
+
+	report, err := Push("CP_WECHAT,"+server.URL+"/busy", "SSL", []Event{ExpiryEvent(SourceDomain, "x.com", "SSL", 3, 15)})
+	if err != nil || requests != 2 || report.Chunks != 1 || len(report.Failed) != 0 {
+		test.Fatalf("unexpected report: %+v %v (requests %d)", report, err, requests)
+	}
+
+	requests = 0
+	report, err = Push("CP_WECHAT,"+server.URL+"/invalid", "SSL", []Event{ExpiryEvent(SourceDomain, "x.com", "SSL", 3, 15)})
+	if err == nil || requests != 1 || len(report.Failed) != 1 || !strings.Contains(report.Failed[0].Error, "93000") {
+		test.Fatalf("unexpected report: %+v %v (requests %d)", report, err, requests)
+	}
+}
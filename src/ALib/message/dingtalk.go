@@ -0,0 +1,74 @@
+package message
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/longyuan/lib.v3/logger"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// dingTalkMarkdownBytes 钉钉 Markdown 内容最大字节数
+	dingTalkMarkdownBytes = 20000
+	// dingTalkQuota 钉钉机器人每分钟最多发送 20 条消息
+	dingTalkQuota = 20
+	// dingTalkRateLimitCode 钉钉限流错误码
+	dingTalkRateLimitCode = 130101
+)
+
+// dingTalkColors 钉钉 Markdown 颜色
+var dingTalkColors = map[Level]string{
+	LevelWarning:  "#FF9900",
+	LevelCritical: "#FF0000",
+}
+
+// pushDingTalk 钉钉机器人; config 格式: Webhook 地址[,加签密钥]
+func pushDingTalk(config string, title string, events []Event) (*Report, error) {
+	var webhook, secret = config, ""
+	if index := strings.LastIndex(config, ","); index > -1 {
+		webhook, secret = config[:index], config[index+1:]
+	}
+	var content = markdown(title, events, func(level Level) string {
+		return dingTalkColors[level]
+	})
+
+	logger.Success("[钉钉机器人] 开始推送消息: " + content)
+
+	var report = deliver("DINGTALK", webhook, dingTalkQuota, splitBytes(content, dingTalkMarkdownBytes), func(chunk string) error {
+		requestURL, err := dingTalkSign(webhook, secret, time.Now())
+		if err != nil {
+			return &deliveryError{err: err}
+		}
+		return postJSON(requestURL, map[string]any{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"title": title,
+				"text":  chunk,
+			},
+		}, checkErrCode(dingTalkRateLimitCode))
+	})
+	return report, report.Err()
+}
+
+// dingTalkSign 加签; 未配置密钥时返回原地址
+func dingTalkSign(webhook, secret string, now time.Time) (string, error) {
+	if secret == "" {
+		return webhook, nil
+	}
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "", err
+	}
+	var timestamp = strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
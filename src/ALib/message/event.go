@@ -0,0 +1,200 @@
+package message
+
+import (
+	"fmt"
+	"github.com/longyuan/lib.v3/ctl"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SourceDomain     = "domain"
+	SourceNacos      = "nacos"
+	SourceGitlab     = "gitlab"
+	SourceKubernetes = "kubernetes"
+)
+
+// Level 告警级别
+type Level int
+
+const (
+	LevelInfo Level = iota
+	LevelWarning
+	LevelCritical
+)
+
+var levelNames = []string{"info", "warning", "critical"}
+
+func (level Level) String() string {
+	if level < 0 || int(level) >= len(levelNames) {
+		return strconv.Itoa(int(level))
+	}
+	return levelNames[level]
+}
+
+// ParseLevel 解析告警级别 (info, warning, critical)
+func ParseLevel(value string) (Level, error) {
+	for index, name := range levelNames {
+		if strings.EqualFold(strings.TrimSpace(value), name) {
+			return Level(index), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("level not supported: %s", value)
+}
+
+func (level Level) MarshalText() ([]byte, error) {
+	return []byte(level.String()), nil
+}
+
+func (level *Level) UnmarshalText(text []byte) error {
+	value, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*level = value
+	return nil
+}
+
+// Event 告警事件
+type Event struct {
+	// Source 来源工具 (domain, nacos, gitlab, kubernetes)
+	Source string `json:"source"`
+	// Subject 告警对象 (域名、配置文件)
+	Subject string `json:"subject"`
+	// Check 检查项 (SSL, Whois, Backup)
+	Check string `json:"check"`
+	// Level 告警级别
+	Level Level `json:"level"`
+	// Days 剩余天数, 负数为已过期
+	Days *int `json:"days,omitempty"`
+	// Message 补充说明 (如上传路径)
+	Message string `json:"message,omitempty"`
+	// Error 异常信息
+	Error string `json:"error,omitempty"`
+	// Labels 标签 (域名分组、集群名称)
+	Labels map[string]string `json:"labels,omitempty"`
+	// Resolved 异常已恢复
+	Resolved bool `json:"resolved,omitempty"`
+	// Time 事件时间
+	Time time.Time `json:"time"`
+}
+
+// NewEvent 创建告警事件; err 不为空时级别为 LevelCritical
+func NewEvent(source, subject, check string, level Level, err error) Event {
+	var event = Event{Source: source, Subject: subject, Check: check, Level: level, Time: time.Now()}
+	if err != nil {
+		event.Level = LevelCritical
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// ExpiryEvent 到期检查事件; 已过期为 LevelCritical, 少于 warningDays 天为 LevelWarning
+func ExpiryEvent(source, subject, check string, days int, warningDays int) Event {
+	var level = LevelInfo
+	if days < 0 {
+		level = LevelCritical
+	} else if days < warningDays {
+		level = LevelWarning
+	}
+	var event = NewEvent(source, subject, check, level, nil)
+	event.Days = &days
+	return event
+}
+
+// Status 状态描述; 如 剩余: 30天, 即将过期: 3天, 已过期: 1天, 失败: xxx, 已恢复
+func (event Event) Status() string {
+	if event.Resolved {
+		if event.Days != nil {
+			return "已恢复, 剩余: " + strconv.Itoa(*event.Days) + "天"
+		}
+		return "已恢复"
+	}
+	if event.Error != "" {
+		return "失败: " + event.Error
+	}
+	if event.Days != nil {
+		var days = *event.Days
+		if days < 0 {
+			return "已过期: " + strconv.Itoa(int(math.Abs(float64(days)))) + "天"
+		}
+		if event.Level >= LevelWarning {
+			return "即将过期: " + strconv.Itoa(days) + "天"
+		}
+		return "剩余: " + strconv.Itoa(days) + "天"
+	}
+	if event.Level >= LevelWarning {
+		return "异常"
+	}
+	return "成功"
+}
+
+// Text 纯文本描述; 如 x.com SSL ( 剩余: 30天 )
+func (event Event) Text() string {
+	var value = event.Subject + " " + event.Check + " ( " + event.Status() + " )"
+	if event.Message != "" {
+		value += " " + event.Message
+	}
+	return value
+}
+
+// MaxLevel 事件中的最高级别
+func MaxLevel(events []Event) Level {
+	var level = LevelInfo
+	for _, event := range events {
+		if event.Level > level {
+			level = event.Level
+		}
+	}
+	return level
+}
+
+// FilterLevel 过滤低于 level 的事件
+func FilterLevel(events []Event, level Level) []Event {
+	var result []Event
+	for _, event := range events {
+		if event.Level >= level {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// Header 消息头 (标题、版本号、检查时间)
+func Header(title string) string {
+	return "## " + title + "\n" +
+		"> 程序版本号：**" + ctl.Version + "** \n" +
+		"> 检查时间：**" + time.Now().Format(time.DateOnly) + "**\n"
+}
+
+// markdown 渲染 Markdown 消息; font 返回级别对应的颜色, 空串表示不着色
+func markdown(title string, events []Event, font func(level Level) string) string {
+	return Header(title) + markdownEvents(events, font)
+}
+
+// markdownEvents 渲染事件的 Markdown 引用行, 见 markdown
+func markdownEvents(events []Event, font func(level Level) string) string {
+	var content string
+	for _, event := range events {
+		var value = event.Subject + " **" + event.Check + " ( " + event.Status() + " )** "
+		if event.Message != "" {
+			value += event.Message
+		}
+		if fontColor := font(event.Level); fontColor != "" {
+			value = "<font color=\"" + fontColor + "\">" + value + "</font>"
+		}
+		content += "> " + value + "\n"
+	}
+	return content
+}
+
+// Text 渲染纯文本消息
+func Text(title string, events []Event) string {
+	var rows = []string{title, "检查时间: " + time.Now().Format(time.DateOnly)}
+	for _, event := range events {
+		rows = append(rows, "["+strings.ToUpper(event.Level.String())+"] "+event.Text())
+	}
+	return strings.Join(rows, "\n") + "\n"
+}
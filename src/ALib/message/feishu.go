@@ -0,0 +1,118 @@
+package message
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// feiShuMarkdownBytes 飞书卡片内容最大字节数 (请求体不超过 30KB)
+	feiShuMarkdownBytes = 20000
+	// feiShuQuota 飞书机器人每分钟最多发送 100 条消息
+	feiShuQuota = 100
+	// feiShuRateLimitCode 飞书限流错误码
+	feiShuRateLimitCode = 11232
+)
+
+// feiShuColors 飞书卡片 Markdown 颜色
+var feiShuColors = map[Level]string{
+	LevelWarning:  "orange",
+	LevelCritical: "red",
+}
+
+// feiShuTemplates 飞书卡片标题颜色
+var feiShuTemplates = map[Level]string{
+	LevelInfo:     "blue",
+	LevelWarning:  "orange",
+	LevelCritical: "red",
+}
+
+// pushFeiShu 飞书 / Lark 机器人 (卡片消息); config 格式: Webhook 地址[,签名密钥]
+func pushFeiShu(config string, title string, events []Event) (*Report, error) {
+	var webhook, secret = config, ""
+	if index := strings.LastIndex(config, ","); index > -1 {
+		webhook, secret = config[:index], config[index+1:]
+	}
+	var content = feiShuMarkdown(events)
+
+	logger.Success("[飞书机器人] 开始推送消息: " + content)
+
+	var report = deliver("FEISHU", webhook, feiShuQuota, splitBytes(content, feiShuMarkdownBytes), func(chunk string) error {
+		var requestBody = map[string]any{
+			"msg_type": "interactive",
+			"card": map[string]any{
+				"config": map[string]any{"wide_screen_mode": true},
+				"header": map[string]any{
+					"template": feiShuTemplates[MaxLevel(events)],
+					"title":    map[string]string{"tag": "plain_text", "content": title},
+				},
+				"elements": []any{
+					map[string]string{"tag": "markdown", "content": chunk},
+				},
+			},
+		}
+		if secret != "" {
+			var timestamp = time.Now().Unix()
+			sign, err := feiShuSign(secret, timestamp)
+			if err != nil {
+				return &deliveryError{err: err}
+			}
+			requestBody["timestamp"] = strconv.FormatInt(timestamp, 10)
+			requestBody["sign"] = sign
+		}
+		return postJSON(webhook, requestBody, checkFeiShu)
+	})
+	return report, report.Err()
+}
+
+// checkFeiShu 校验 {"code": 0, "msg": "success"} 格式的响应
+func checkFeiShu(body []byte) error {
+	var result = struct {
+		Code *int   `json:"code"`
+		Msg  string `json:"msg"`
+	}{}
+	err := json.Unmarshal(body, &result)
+	if err != nil {
+		return &deliveryError{err: fmt.Errorf("response error: %s", strings.TrimSpace(string(body)))}
+	}
+	if result.Code == nil || *result.Code == 0 {
+		return nil
+	}
+	err = fmt.Errorf("code %d: %s", *result.Code, result.Msg)
+	if *result.Code == feiShuRateLimitCode {
+		return retryable(err)
+	}
+	return &deliveryError{err: err}
+}
+
+// feiShuSign 签名校验; 以 timestamp + "\n" + 密钥 作为 HmacSHA256 的 Key 对空串签名
+func feiShuSign(secret string, timestamp int64) (string, error) {
+	mac := hmac.New(sha256.New, []byte(strconv.FormatInt(timestamp, 10)+"\n"+secret))
+	_, err := mac.Write([]byte{})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// feiShuMarkdown 卡片正文; 标题由卡片头展示, 卡片 Markdown 不支持引用语法
+func feiShuMarkdown(events []Event) string {
+	var content = "程序版本号：**" + ctl.Version + "**\n" +
+		"检查时间：**" + time.Now().Format(time.DateOnly) + "**\n"
+	for _, event := range events {
+		var value = event.Subject + " **" + event.Check + " ( " + event.Status() + " )** " + event.Message
+		if fontColor, ok := feiShuColors[event.Level]; ok {
+			value = "<font color='" + fontColor + "'>" + value + "</font>"
+		}
+		content += value + "\n"
+	}
+	return content
+}
@@ -1,76 +1,66 @@
 package message
 
 import (
-	"bytes"
-	"encoding/json"
-	"github.com/fatih/color"
+	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
-var client = http.Client{}
+var client = http.Client{Timeout: 30 * time.Second}
 
-const DomainType = "DOMAIN"
+// Notifier 消息通知渠道; config 渠道配置 (去掉渠道名称后的部分), title 标题, events 告警事件, 由渠道负责渲染;
+// 返回推送结果, 存在失败分片时同时返回异常
+type Notifier interface {
+	Notify(config string, title string, events []Event) (*Report, error)
+}
 
-var handler = map[string]func(config string, title string, message string) error{
-	"DOMAIN:CP_WECHAT": func(config string, title string, message string) error {
-		var content = "## " + title + "\n" +
-			"> 程序版本号：**1.0.2** \n" +
-			"> 检查时间：**#{now}**\n"
-		content += message + "\n"
-		content = ParseContent(content)
+// NotifierFunc 函数形式的消息通知渠道
+type NotifierFunc func(config string, title string, events []Event) (*Report, error)
 
-		color.Green("[企业微信机器人] 开始推送消息: " + content)
+func (f NotifierFunc) Notify(config string, title string, events []Event) (*Report, error) {
+	return f(config, title, events)
+}
 
-		var rows = strings.Split(content, "\n")
-		var length = len(rows)
-		var index int
-		if length%60 == 0 {
-			index = length / 60
-		} else {
-			index = length/60 + 1
-		}
+var handlerLock sync.RWMutex
+var handler = map[string]Notifier{}
 
-		for i := 0; i < index; i++ {
-			if index-1 == i {
-				content = strings.Join(rows[i*60:], "\n")
-			} else {
-				content = strings.Join(rows[i*60:(i+1)*60], "\n")
-			}
-			var requestBody = map[string]interface{}{}
-			var contentRequest = map[string]string{}
-			contentRequest["content"] = content
-			requestBody["msgtype"] = "markdown"
-			requestBody["markdown"] = contentRequest
-			requestByteData, err := json.Marshal(requestBody)
-			if err != nil {
-				panic(err)
-			}
-			request, err := http.NewRequest("POST", config, bytes.NewReader(requestByteData))
-			if err != nil {
-				panic(err)
-			}
-			_, err = client.Do(request)
-			if err != nil {
-				return err
-			}
-			time.Sleep(time.Second)
-		}
-		return nil
-	},
+func init() {
+	Register("CP_WECHAT", NotifierFunc(pushWeChat))
+	Register("DINGTALK", NotifierFunc(pushDingTalk))
+	Register("FEISHU", NotifierFunc(pushFeiShu))
+	Register("LARK", NotifierFunc(pushFeiShu))
+	Register("SLACK", NotifierFunc(pushSlack))
+	Register("EMAIL", NotifierFunc(pushEmail))
+	Register("WEBHOOK", NotifierFunc(pushWebhook))
 }
 
-func Push(messageFormatType, config string, title string, message string) error {
-	var configs = strings.Split(config, ",")
-	var messageType = configs[0]
-	config = config[len(messageType)+1:]
-	if fun, ok := handler[messageFormatType+":"+messageType]; ok {
-		return fun(config, title, message)
-	}
-	return nil
+// Register 注册消息通知渠道; messageType 渠道名称 (如 CP_WECHAT)
+func Register(messageType string, notifier Notifier) {
+	handlerLock.Lock()
+	defer handlerLock.Unlock()
+	handler[messageType] = notifier
 }
 
-func ParseContent(message string) string {
-	return strings.ReplaceAll(message, "#{now}", time.Now().Format(time.DateOnly))
+// Lookup 查找已注册的消息通知渠道
+func Lookup(messageType string) (Notifier, bool) {
+	handlerLock.RLock()
+	defer handlerLock.RUnlock()
+	notifier, ok := handler[messageType]
+	return notifier, ok
+}
+
+// Push 推送告警事件; config 格式: 渠道名称,渠道配置 (如 CP_WECHAT,https://qyapi.weixin.qq.com/...)
+func Push(config string, title string, events []Event) (*Report, error) {
+	var configs = strings.SplitN(config, ",", 2)
+	if len(configs) < 2 {
+		return nil, fmt.Errorf("message config error: %s", configs[0])
+	}
+	var messageType = configs[0]
+	notifier, ok := Lookup(messageType)
+	if !ok {
+		return nil, fmt.Errorf("message type not supported: %s", messageType)
+	}
+	return notifier.Notify(configs[1], title, events)
 }
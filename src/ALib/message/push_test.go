@@ -0,0 +1,152 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/longyuan/lib.v3/ctl"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// webhook 本地 Webhook 模拟服务, 记录收到的请求
+func webhook(test *testing.T) (*httptest.Server, *[]map[string]any, *[]string) {
+	var bodies []map[string]any
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			test.Fatal(err)
+		}
+		var body = map[string]any{}
+		err = json.Unmarshal(data, &body)
+		if err != nil {
+			test.Fatal(err)
+		}
+		bodies = append(bodies, body)
+		queries = append(queries, r.URL.RawQuery)
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	test.Cleanup(server.Close)
+	return server, &bodies, &queries
+}
+
+func TestPushDingTalk(test *testing.T) {
+	server, bodies, queries := webhook(test)
+	_, err := Push("DINGTALK,"+server.URL+"/robot/send?access_token=x,SEC000", "SSL", []Event{
+		ExpiryEvent("domain", "x.com", "SSL", -3, 15),
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(*bodies) != 1 || (*bodies)[0]["msgtype"] != "markdown" {
+		test.Fatalf("unexpected body: %v", *bodies)
+	}
+	text := (*bodies)[0]["markdown"].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "## SSL") || !strings.Contains(text, "<font color=\"#FF0000\">x.com **SSL ( 已过期: 3天 )** </font>") {
+		test.Fatalf("unexpected text: %s", text)
+	}
+	if !strings.Contains((*queries)[0], "access_token=x") || !strings.Contains((*queries)[0], "sign=") {
+		test.Fatalf("unexpected query: %s", (*queries)[0])
+	}
+}
+
+func TestPushFeiShu(test *testing.T) {
+	server, bodies, _ := webhook(test)
+	_, err := Push("FEISHU,"+server.URL+",SEC000", "SSL", []Event{
+		ExpiryEvent("domain", "x.com", "SSL", 30, 15),
+		ExpiryEvent("domain", "y.com", "SSL", 3, 15),
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	body := (*bodies)[0]
+	if body["msg_type"] != "interactive" || body["sign"] == nil {
+		test.Fatalf("unexpected body: %v", body)
+	}
+	card := body["card"].(map[string]any)
+	header := card["header"].(map[string]any)
+	if header["title"].(map[string]any)["content"] != "SSL" || header["template"] != "orange" {
+		test.Fatalf("unexpected header: %v", header)
+	}
+}
+
+func TestPushSlack(test *testing.T) {
+	server, bodies, _ := webhook(test)
+	_, err := Push("SLACK,"+server.URL, "SSL", []Event{
+		ExpiryEvent("domain", "x.com", "SSL", 3, 15),
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	text := (*bodies)[0]["text"].(string)
+	if !strings.HasPrefix(text, "*SSL*") || !strings.Contains(text, ":warning: x.com *SSL ( 即将过期: 3天 )*") {
+		test.Fatalf("unexpected text: %s", text)
+	}
+}
+
+func TestPushRegister(test *testing.T) {
+	var received string
+	Register("TEST", NotifierFunc(func(config string, title string, events []Event) (*Report, error) {
+		received = config + "|" + title + "|" + events[0].Subject
+		return &Report{Channel: "TEST", Chunks: 1}, nil
+	}))
+	_, err := Push("TEST,a,b", "t", []Event{NewEvent("domain", "m", "SSL", LevelInfo, nil)})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if received != "a,b|t|m" {
+		test.Fatalf("unexpected: %s", received)
+	}
+	if _, err = Push("UNKNOWN,x", "t", nil); err == nil {
+		test.Fatal("expected error for unknown type")
+	}
+}
+
+func TestPushWebhook(test *testing.T) {
+	server, bodies, queries := webhook(test)
+	var configPath = test.TempDir() + "/webhook.yaml"
+	err := os.WriteFile(configPath, []byte(`
+url: "`+server.URL+`/alert?severity={{.Severity}}"
+headers:
+  Authorization: Bearer TOKEN
+body: |
+  {"summary": {{json .Title}}, "level": {{json (upper .Severity)}}, "detail": {{json .Text}}, "ts": {{unix .Timestamp}}, "message": {{json .Message}}, "content": {{json .Content}}}
+`), 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	_, err = Push("WEBHOOK,"+configPath, "Nacos 备份", []Event{
+		BackupEvent("nacos", "prod.yaml", "", errors.New("timeout")),
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	body := (*bodies)[0]
+	if body["summary"] != "Nacos 备份" || body["level"] != "CRITICAL" || !strings.Contains(body["detail"].(string), "prod.yaml Backup ( 失败: timeout )") {
+		test.Fatalf("unexpected body: %v", body)
+	}
+	// 兼容旧模板的 Message 和 Content
+	if body["message"] != "> <font color=\"red\">prod.yaml **Backup ( 失败: timeout )** </font>\n" || !strings.HasPrefix(body["content"].(string), "## Nacos 备份\n> 程序版本号：**"+ctl.Version+"**") || !strings.HasSuffix(body["content"].(string), body["message"].(string)) {
+		test.Fatalf("unexpected body: %v", body)
+	}
+	if (*queries)[0] != "severity=critical" {
+		test.Fatalf("unexpected query: %s", (*queries)[0])
+	}
+
+	_, err = Push("WEBHOOK,"+server.URL, "SSL", []Event{ExpiryEvent("domain", "x.com", "SSL", 30, 15)})
+	if err != nil {
+		test.Fatal(err)
+	}
+	body = (*bodies)[1]
+	if body["severity"] != "info" || body["title"] != "SSL" {
+		test.Fatalf("unexpected body: %v", body)
+	}
+	event := body["events"].([]any)[0].(map[string]any)
+	if event["subject"] != "x.com" || event["level"] != "info" || event["days"] != float64(30) {
+		test.Fatalf("unexpected event: %v", event)
+	}
+}
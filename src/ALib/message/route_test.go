@@ -0,0 +1,111 @@
+package message
+
+import (
+	"github.com/longyuan/lib.v3/secret"
+	"github.com/longyuan/lib.v3/times"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouterDispatch(test *testing.T) {
+	var received = map[string][]string{}
+	Register("CAPTURE", NotifierFunc(func(config string, title string, events []Event) (*Report, error) {
+		for _, event := range events {
+			received[config] = append(received[config], title+"|"+event.Subject)
+		}
+		return &Report{Channel: "CAPTURE", Chunks: 1}, nil
+	}))
+	var configPath = test.TempDir() + "/route.yaml"
+	err := os.WriteFile(configPath, []byte(`
+channels:
+  ops: CAPTURE,ops
+  sre: CAPTURE,sre
+  backup: CAPTURE,backup
+routes:
+  - name: domain
+    sources: [domain]
+    labels: {domain: "*.com"}
+    minLevel: warning
+    quietHours: "22:00-08:00"
+    channels: [ops]
+    escalate: {after: 2, channels: [sre]}
+  - name: backup
+    sources: [nacos, gitlab, kubernetes]
+    checks: [Backup]
+    channels: [backup]
+`), 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	router, err := NewRouter(configPath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	router.now = func() time.Time {
+		return time.Date(2023, 7, 26, 12, 0, 0, 0, times.In(time.Now()).Location())
+	}
+	var events = func() []Event {
+		var expiring = ExpiryEvent(SourceDomain, "a.com", "SSL", 3, 15)
+		expiring.Labels = map[string]string{"domain": "a.com"}
+		var healthy = ExpiryEvent(SourceDomain, "b.com", "SSL", 60, 15)
+		healthy.Labels = map[string]string{"domain": "b.com"}
+		return []Event{expiring, healthy, BackupEvent(SourceNacos, "prod.yaml", "nacos/x.zip", nil)}
+	}
+
+	_, err = router.Dispatch("SSL", events())
+	if err != nil {
+		test.Fatal(err)
+	}
+	if strings.Join(received["ops"], ",") != "SSL|a.com" || len(received["sre"]) != 0 || strings.Join(received["backup"], ",") != "SSL|prod.yaml" {
+		test.Fatalf("unexpected dispatch: %v", received)
+	}
+
+	// 第二次运行仍异常, 升级
+	_, err = router.Dispatch("SSL", events())
+	if err != nil {
+		test.Fatal(err)
+	}
+	if strings.Join(received["sre"], ",") != "SSL [升级]|a.com" {
+		test.Fatalf("unexpected escalate: %v", received)
+	}
+
+	// 免打扰时段只推送 critical
+	received = map[string][]string{}
+	router.now = func() time.Time {
+		return time.Date(2023, 7, 26, 23, 0, 0, 0, times.In(time.Now()).Location())
+	}
+	_, err = router.Dispatch("SSL", events())
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(received["ops"]) != 0 {
+		test.Fatalf("unexpected quiet hours dispatch: %v", received)
+	}
+}
+
+func TestRouterValidate(test *testing.T) {
+	_, err := newRouter(RouteConfig{
+		Channels: map[string]string{"ops": "CP_WECHAT,https://x"},
+		Routes:   []Route{{Name: "r", Channels: []string{"missing"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "channel not defined: missing") {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	router, err := NewRouter("CP_WECHAT,https://x")
+	if err != nil || router.config.Channels["default"] != "CP_WECHAT,https://x" {
+		test.Fatalf("unexpected router: %v", err)
+	}
+}
+
+func TestRouterRegisterSecrets(test *testing.T) {
+	_, err := NewRouter("DINGTALK,https://oapi.example.com/robot/send?access_token=token9f8e7d,SEC1a2b3c")
+	if err != nil {
+		test.Fatal(err)
+	}
+	var text = secret.Redact("push failed: https://oapi.example.com/robot/send?access_token=token9f8e7d&timestamp=1 sign SEC1a2b3c")
+	if strings.Contains(text, "token9f8e7d") || strings.Contains(text, "SEC1a2b3c") {
+		test.Fatal(text)
+	}
+}
@@ -0,0 +1,54 @@
+package message
+
+import (
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"strings"
+	"time"
+)
+
+const (
+	// slackTextBytes Slack 消息内容最大字节数
+	slackTextBytes = 40000
+	// slackQuota Slack Incoming Webhook 每秒最多 1 条消息
+	slackQuota = 60
+)
+
+// slackEmoji Slack 级别图标
+var slackEmoji = map[Level]string{
+	LevelInfo:     ":large_green_circle:",
+	LevelWarning:  ":warning:",
+	LevelCritical: ":red_circle:",
+}
+
+// pushSlack Slack Incoming Webhook; config Webhook 地址
+func pushSlack(config string, title string, events []Event) (*Report, error) {
+	var content = slackMarkdown(title, events)
+
+	logger.Success("[Slack] 开始推送消息: " + content)
+
+	var report = deliver("SLACK", config, slackQuota, splitBytes(content, slackTextBytes), func(chunk string) error {
+		return postJSON(config, map[string]any{
+			"text":   chunk,
+			"mrkdwn": true,
+		}, nil)
+	})
+	return report, report.Err()
+}
+
+// slackMarkdown 渲染 Slack mrkdwn 消息
+func slackMarkdown(title string, events []Event) string {
+	var rows = []string{
+		"*" + title + "*",
+		"> 程序版本号：*" + ctl.Version + "*",
+		"> 检查时间：*" + time.Now().Format(time.DateOnly) + "*",
+	}
+	for _, event := range events {
+		var value = slackEmoji[event.Level] + " " + event.Subject + " *" + event.Check + " ( " + event.Status() + " )*"
+		if event.Message != "" {
+			value += " " + event.Message
+		}
+		rows = append(rows, value)
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,160 @@
+package message
+
+import (
+	"github.com/longyuan/lib.v3/state"
+	"github.com/longyuan/lib.v3/times"
+	"time"
+)
+
+const (
+	StateOK       = "ok"
+	StateExpiring = "expiring"
+	StateExpired  = "expired"
+	StateFailing  = "failing"
+)
+
+const (
+	trackerBucket = "alerts"
+	digestBucket  = "digest"
+)
+
+// EventState 事件状态: ok, expiring (即将过期), expired (已过期), failing (失败)
+func EventState(event Event) string {
+	if event.Error != "" {
+		return StateFailing
+	}
+	if event.Days != nil && *event.Days < 0 {
+		return StateExpired
+	}
+	if event.Level >= LevelWarning {
+		if event.Days != nil {
+			return StateExpiring
+		}
+		return StateFailing
+	}
+	return StateOK
+}
+
+// Record 事件最近一次状态
+type Record struct {
+	State string `json:"state"`
+	// Failures 连续异常次数
+	Failures int `json:"failures"`
+	// Changed 状态变化时间
+	Changed time.Time `json:"changed"`
+	// Event 最近一次事件
+	Event Event `json:"event"`
+}
+
+// Observation 事件观测结果
+type Observation struct {
+	// Event 事件, 恢复时为 Resolved 事件
+	Event Event
+	// Changed 状态是否变化 (首次观测到正常状态不算变化)
+	Changed bool
+	// Failures 连续异常次数
+	Failures int
+	// AlertLevel 恢复事件之前告警的级别; 路由按此级别匹配, 使恢复通知发送到原告警的渠道
+	AlertLevel Level
+
+	// key, record 推送成功后由 Tracker.Commit 保存的状态
+	key    string
+	record *Record
+}
+
+// Tracker 告警状态跟踪; 持久化每个事件的状态, 用于去重、恢复通知和每日汇总
+type Tracker struct {
+	store *state.Store
+}
+
+func NewTracker(store *state.Store) *Tracker {
+	return &Tracker{store: store}
+}
+
+// Observe 根据保存的状态计算事件的观测结果, 不修改状态; 推送成功后调用 Commit 保存
+func (tracker *Tracker) Observe(events []Event) ([]Observation, error) {
+	var observations = make([]Observation, len(events))
+	for index, event := range events {
+		var key = eventKey(event)
+		var record Record
+		exist, err := tracker.store.Get(trackerBucket, key, &record)
+		if err != nil {
+			return nil, err
+		}
+		var current = EventState(event)
+		var observation = Observation{Event: event, key: key}
+		if current != StateOK {
+			record.Failures++
+		} else {
+			record.Failures = 0
+		}
+		if !exist {
+			observation.Changed = current != StateOK
+			record.Changed = event.Time
+		} else if record.State != current {
+			observation.Changed = true
+			record.Changed = event.Time
+			if current == StateOK {
+				observation.Event.Resolved = true
+				observation.AlertLevel = record.Event.Level
+			}
+		}
+		observation.Failures = record.Failures
+		record.State = current
+		record.Event = event
+		observation.record = &record
+		observations[index] = observation
+	}
+	return observations, nil
+}
+
+// Commit 保存观测结果的状态 (一次写入); 推送失败的事件不保存, 下次运行时重新推送
+func (tracker *Tracker) Commit(observations []Observation) error {
+	return tracker.store.Update(func(tx *state.Tx) error {
+		for _, observation := range observations {
+			if observation.record == nil {
+				continue
+			}
+			err := tx.Put(trackerBucket, observation.key, observation.record)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Events 来源工具的所有事件最近一次状态
+func (tracker *Tracker) Events(source string) ([]Event, error) {
+	var events []Event
+	for _, key := range tracker.store.Keys(trackerBucket) {
+		var record Record
+		_, err := tracker.store.Get(trackerBucket, key, &record)
+		if err != nil {
+			return nil, err
+		}
+		if source == "" || record.Event.Source == source {
+			events = append(events, record.Event)
+		}
+	}
+	return events, nil
+}
+
+// DigestDue 每日汇总是否到期; name 汇总名称, hour 每日汇总时间 (0-23)
+func (tracker *Tracker) DigestDue(name string, now time.Time, hour int) (bool, error) {
+	now = times.In(now)
+	if now.Hour() < hour {
+		return false, nil
+	}
+	var last string
+	_, err := tracker.store.Get(digestBucket, name, &last)
+	if err != nil {
+		return false, err
+	}
+	return last != now.Format(time.DateOnly), nil
+}
+
+// DigestSent 记录每日汇总已发送
+func (tracker *Tracker) DigestSent(name string, now time.Time) error {
+	return tracker.store.Put(digestBucket, name, times.In(now).Format(time.DateOnly))
+}
@@ -0,0 +1,191 @@
+package message
+
+import (
+	"errors"
+	"github.com/longyuan/lib.v3/state"
+	"github.com/longyuan/lib.v3/times"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackerDispatch(test *testing.T) {
+	var received []string
+	Register("TRACK", NotifierFunc(func(config string, title string, events []Event) (*Report, error) {
+		for _, event := range events {
+			received = append(received, title+"|"+event.Subject+"|"+event.Status())
+		}
+		return &Report{Channel: "TRACK", Chunks: 1}, nil
+	}))
+	var storePath = test.TempDir() + "/state.json"
+	store, err := state.Open(storePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	router, err := NewRouter("TRACK,x")
+	if err != nil {
+		test.Fatal(err)
+	}
+	router.Track(NewTracker(store))
+	var run = func(days ...int) string {
+		received = nil
+		var events []Event
+		for index, day := range days {
+			events = append(events, ExpiryEvent(SourceDomain, []string{"a.com", "b.com"}[index], "SSL", day, 15))
+		}
+		_, err := router.Dispatch("SSL", events)
+		if err != nil {
+			test.Fatal(err)
+		}
+		return strings.Join(received, ",")
+	}
+
+	if value := run(60, 3); value != "SSL|b.com|即将过期: 3天" {
+		test.Fatalf("first run: %s", value)
+	}
+	if value := run(59, 2); value != "" {
+		test.Fatalf("unchanged run: %s", value)
+	}
+	if value := run(58, -1); value != "SSL|b.com|已过期: 1天" {
+		test.Fatalf("expired run: %s", value)
+	}
+
+	// 重新打开存储, 状态保留
+	store, err = state.Open(storePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	router.Track(NewTracker(store))
+	if value := run(57, 90); value != "SSL|b.com|已恢复, 剩余: 90天" {
+		test.Fatalf("resolved run: %s", value)
+	}
+
+	// 每日汇总只推送一次
+	router.now = func() time.Time {
+		return time.Date(2023, 7, 26, 10, 0, 0, 0, time.UTC)
+	}
+	received = nil
+	_, err = router.Digest("汇总", SourceDomain, 9)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(received) != 2 {
+		test.Fatalf("digest: %v", received)
+	}
+	received = nil
+	_, err = router.Digest("汇总", SourceDomain, 9)
+	if err != nil || len(received) != 0 {
+		test.Fatalf("digest twice: %v %v", received, err)
+	}
+}
+
+func TestTrackerResolvedRoute(test *testing.T) {
+	var received []string
+	var fail = true
+	Register("FLAKY", NotifierFunc(func(config string, title string, events []Event) (*Report, error) {
+		if fail {
+			return nil, errors.New("push failed")
+		}
+		for _, event := range events {
+			received = append(received, event.Subject+"|"+event.Status())
+		}
+		return &Report{Channel: "FLAKY", Chunks: 1}, nil
+	}))
+	var configPath = test.TempDir() + "/route.yaml"
+	err := os.WriteFile(configPath, []byte(`
+channels:
+  ops: FLAKY,ops
+routes:
+  - name: ssl
+    minLevel: warning
+    channels: [ops]
+`), 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	router, err := NewRouter(configPath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	store, err := state.Open(test.TempDir() + "/state.json")
+	if err != nil {
+		test.Fatal(err)
+	}
+	router.Track(NewTracker(store))
+
+	// 推送失败时不保存状态, 下次运行重新推送
+	_, err = router.Dispatch("SSL", []Event{ExpiryEvent(SourceDomain, "a.com", "SSL", 3, 15)})
+	if err == nil || len(store.Keys(trackerBucket)) != 0 {
+		test.Fatalf("failed push committed: %v %v", err, store.Keys(trackerBucket))
+	}
+	fail = false
+	_, err = router.Dispatch("SSL", []Event{ExpiryEvent(SourceDomain, "a.com", "SSL", 2, 15)})
+	if err != nil || strings.Join(received, ",") != "a.com|即将过期: 2天" {
+		test.Fatalf("retry: %v %v", received, err)
+	}
+
+	// 恢复事件 (info) 按之前告警的级别匹配 minLevel
+	received = nil
+	_, err = router.Dispatch("SSL", []Event{ExpiryEvent(SourceDomain, "a.com", "SSL", 90, 15)})
+	if err != nil || strings.Join(received, ",") != "a.com|已恢复, 剩余: 90天" {
+		test.Fatalf("resolved: %v %v", received, err)
+	}
+	received = nil
+	_, err = router.Dispatch("SSL", []Event{ExpiryEvent(SourceDomain, "a.com", "SSL", 89, 15)})
+	if err != nil || len(received) != 0 {
+		test.Fatalf("ok run: %v %v", received, err)
+	}
+}
+
+func TestTrackerQuietHours(test *testing.T) {
+	var received []string
+	Register("QUIET", NotifierFunc(func(config string, title string, events []Event) (*Report, error) {
+		for _, event := range events {
+			received = append(received, event.Subject+"|"+event.Status())
+		}
+		return &Report{Channel: "QUIET", Chunks: 1}, nil
+	}))
+	router, err := NewConfigRouter(RouteConfig{
+		Channels: map[string]string{"ops": "QUIET,ops"},
+		Routes:   []Route{{Name: "ssl", QuietHours: "22:00-08:00", Channels: []string{"ops"}}},
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	store, err := state.Open(test.TempDir() + "/state.json")
+	if err != nil {
+		test.Fatal(err)
+	}
+	router.Track(NewTracker(store))
+	var hour = 23
+	router.now = func() time.Time {
+		return time.Date(2023, 7, 26, hour, 0, 0, 0, times.In(time.Now()).Location())
+	}
+
+	// 免打扰时段内的状态变化暂缓, 结束后推送
+	for _, days := range []int{3, 2} {
+		_, err = router.Dispatch("SSL", []Event{ExpiryEvent(SourceDomain, "a.com", "SSL", days, 15)})
+		if err != nil || len(received) != 0 {
+			test.Fatalf("quiet: %v %v", received, err)
+		}
+	}
+	hour = 9
+	_, err = router.Dispatch("SSL", []Event{ExpiryEvent(SourceDomain, "a.com", "SSL", 2, 15)})
+	if err != nil || strings.Join(received, ",") != "a.com|即将过期: 2天" {
+		test.Fatalf("after quiet: %v %v", received, err)
+	}
+
+	// 免打扰时段内恢复, 结束后推送恢复通知
+	received = nil
+	hour = 23
+	_, err = router.Dispatch("SSL", []Event{ExpiryEvent(SourceDomain, "a.com", "SSL", 90, 15)})
+	if err != nil || len(received) != 0 {
+		test.Fatalf("quiet resolved: %v %v", received, err)
+	}
+	hour = 9
+	_, err = router.Dispatch("SSL", []Event{ExpiryEvent(SourceDomain, "a.com", "SSL", 90, 15)})
+	if err != nil || strings.Join(received, ",") != "a.com|已恢复, 剩余: 90天" {
+		test.Fatalf("after quiet resolved: %v %v", received, err)
+	}
+}
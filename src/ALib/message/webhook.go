@@ -0,0 +1,158 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// webhookQuota 通用 Webhook 每分钟最多推送次数
+const webhookQuota = 60
+
+// defaultWebhookBody 未配置 body 时的默认请求体
+const defaultWebhookBody = `{"title": {{json .Title}}, "message": {{json .Text}}, "severity": {{json .Severity}}, "timestamp": {{json .Timestamp}}, "events": {{json .Events}}}`
+
+// WebhookConfig 通用 Webhook 配置; URL、Method、Headers、Body 均为 text/template 模板
+type WebhookConfig struct {
+	URL     string            `yaml:"url" json:"url"`
+	Method  string            `yaml:"method" json:"method"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	Body    string            `yaml:"body" json:"body"`
+}
+
+// WebhookData 模板参数
+type WebhookData struct {
+	// Title 标题
+	Title string
+	// Events 告警事件
+	Events []Event
+	// Text 纯文本内容
+	Text string
+	// Severity 最高级别: info, warning, critical
+	Severity string
+	// Timestamp 推送时间
+	Timestamp time.Time
+	// Message 事件的 Markdown 内容 (企业微信颜色标签)
+	//
+	// Deprecated: 使用 Events 或 Text, 保留用于兼容已有模板
+	Message string
+	// Content 渲染后的 Markdown 内容 (含标题、版本号、检查时间)
+	//
+	// Deprecated: 使用 Title、Events 或 Text, 保留用于兼容已有模板
+	Content string
+}
+
+var webhookFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"unix": func(value time.Time) int64 {
+		return value.Unix()
+	},
+	"rfc3339": func(value time.Time) string {
+		return value.Format(time.RFC3339)
+	},
+}
+
+// LoadWebhookConfig 读取 Webhook 配置; config 为 http(s) 地址时使用默认 JSON 请求体, 否则为 YAML/JSON 配置文件路径
+func LoadWebhookConfig(config string) (*WebhookConfig, error) {
+	if strings.HasPrefix(config, "http://") || strings.HasPrefix(config, "https://") {
+		return &WebhookConfig{URL: config}, nil
+	}
+	fileBytes, err := os.ReadFile(config)
+	if err != nil {
+		return nil, err
+	}
+	var webhookConfig WebhookConfig
+	err = yaml.Unmarshal(fileBytes, &webhookConfig)
+	if err != nil {
+		return nil, err
+	}
+	if webhookConfig.URL == "" {
+		return nil, fmt.Errorf("webhook config url required: %s", config)
+	}
+	return &webhookConfig, nil
+}
+
+// Render 渲染请求; 返回 请求方法, 地址, 请求头, 请求体
+func (webhookConfig *WebhookConfig) Render(data WebhookData) (string, string, map[string]string, []byte, error) {
+	var render = func(name, value string) (string, error) {
+		tpl, err := template.New(name).Funcs(webhookFuncs).Parse(value)
+		if err != nil {
+			return "", err
+		}
+		var buffer bytes.Buffer
+		err = tpl.Execute(&buffer, data)
+		if err != nil {
+			return "", err
+		}
+		return buffer.String(), nil
+	}
+	var method = webhookConfig.Method
+	if method == "" {
+		method = "POST"
+	}
+	method, err := render("method", method)
+	if err != nil {
+		return "", "", nil, nil, err
+	}
+	requestURL, err := render("url", webhookConfig.URL)
+	if err != nil {
+		return "", "", nil, nil, err
+	}
+	var headers = map[string]string{"Content-Type": "application/json"}
+	for key, value := range webhookConfig.Headers {
+		headers[key], err = render("header", value)
+		if err != nil {
+			return "", "", nil, nil, err
+		}
+	}
+	var body = webhookConfig.Body
+	if body == "" {
+		body = defaultWebhookBody
+	}
+	body, err = render("body", body)
+	if err != nil {
+		return "", "", nil, nil, err
+	}
+	return strings.ToUpper(strings.TrimSpace(method)), strings.TrimSpace(requestURL), headers, []byte(body), nil
+}
+
+// pushWebhook 通用 JSON Webhook; config 见 LoadWebhookConfig
+func pushWebhook(config string, title string, events []Event) (*Report, error) {
+	webhookConfig, err := LoadWebhookConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	var message = markdownEvents(events, func(level Level) string {
+		return weChatColors[level]
+	})
+	method, requestURL, headers, body, err := webhookConfig.Render(WebhookData{
+		Title:     title,
+		Events:    events,
+		Text:      Text(title, events),
+		Severity:  MaxLevel(events).String(),
+		Timestamp: time.Now(),
+		Message:   message,
+		Content:   Header(title) + message,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Success(fmt.Sprintf("[Webhook] 开始推送消息: %s %s", method, requestURL))
+
+	var report = deliver("WEBHOOK", requestURL, webhookQuota, []string{string(body)}, func(chunk string) error {
+		return send(method, requestURL, headers, []byte(chunk), nil)
+	})
+	return report, report.Err()
+}
@@ -0,0 +1,37 @@
+package message
+
+import (
+	"github.com/longyuan/lib.v3/logger"
+)
+
+const (
+	// weChatMarkdownBytes 企业微信 Markdown 内容最大字节数
+	weChatMarkdownBytes = 4096
+	// weChatQuota 企业微信机器人每分钟最多发送 20 条消息
+	weChatQuota = 20
+	// weChatRateLimitCode 企业微信限流错误码
+	weChatRateLimitCode = 45009
+)
+
+// weChatColors 企业微信 Markdown 颜色
+var weChatColors = map[Level]string{
+	LevelWarning:  "warning",
+	LevelCritical: "red",
+}
+
+// pushWeChat 企业微信机器人; config 机器人 Webhook 地址
+func pushWeChat(config string, title string, events []Event) (*Report, error) {
+	var content = markdown(title, events, func(level Level) string {
+		return weChatColors[level]
+	})
+
+	logger.Success("[企业微信机器人] 开始推送消息: " + content)
+
+	var report = deliver("CP_WECHAT", config, weChatQuota, splitBytes(content, weChatMarkdownBytes), func(chunk string) error {
+		return postJSON(config, map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": chunk},
+		}, checkErrCode(weChatRateLimitCode))
+	})
+	return report, report.Err()
+}
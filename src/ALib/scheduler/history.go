@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"github.com/longyuan/lib.v3/state"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status 运行状态
+type Status string
+
+const (
+	// StatusRunning 运行中 (进程异常退出时保留该状态)
+	StatusRunning Status = "running"
+	// StatusSuccess 运行成功
+	StatusSuccess Status = "success"
+	// StatusFailed 运行失败
+	StatusFailed Status = "failed"
+	// StatusSkipped 上一次运行尚未结束, 跳过本次运行
+	StatusSkipped Status = "skipped"
+	// StatusCancelled 停止时被取消
+	StatusCancelled Status = "cancelled"
+)
+
+// Run 任务的一次运行记录
+type Run struct {
+	Job    string    `json:"job"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Status Status    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Duration 运行时长; 运行中为到当前的时长
+func (run Run) Duration() time.Duration {
+	if run.End.IsZero() {
+		return time.Since(run.Start)
+	}
+	return run.End.Sub(run.Start)
+}
+
+// historyBucket 运行记录在状态文件中的 bucket, key 为任务名称
+const historyBucket = "history"
+
+// defaultHistoryLimit 每个任务默认保留的运行记录数量
+const defaultHistoryLimit = 100
+
+// History 运行记录; 每个任务保留最近 limit 条 (最新的在前), store 不为空时保存到状态文件
+type History struct {
+	store *state.Store
+	limit int
+	lock  sync.Mutex
+	runs  map[string][]Run
+}
+
+// NewHistory 创建运行记录; store 为空时只保存在内存, limit 小于等于 0 时为 100
+func NewHistory(store *state.Store, limit int) (*History, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	var history = History{store: store, limit: limit, runs: map[string][]Run{}}
+	if store == nil {
+		return &history, nil
+	}
+	for _, name := range store.Keys(historyBucket) {
+		var runs []Run
+		_, err := store.Get(historyBucket, name, &runs)
+		if err != nil {
+			return nil, err
+		}
+		history.runs[name] = runs
+	}
+	return &history, nil
+}
+
+// save 写入运行记录; 同一任务相同开始时间的记录视为同一次运行
+func (history *History) save(run Run) error {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+	var runs = history.runs[run.Job]
+	var updated bool
+	for index := range runs {
+		if runs[index].Start.Equal(run.Start) {
+			runs[index] = run
+			updated = true
+			break
+		}
+	}
+	if !updated {
+		runs = append([]Run{run}, runs...)
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].Start.After(runs[j].Start)
+		})
+	}
+	if len(runs) > history.limit {
+		runs = runs[:history.limit]
+	}
+	history.runs[run.Job] = runs
+	if history.store == nil {
+		return nil
+	}
+	return history.store.Put(historyBucket, run.Job, runs)
+}
+
+// List 任务的运行记录, 最新的在前
+func (history *History) List(job string) []Run {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+	return append([]Run{}, history.runs[job]...)
+}
+
+// Last 任务最近一次运行记录
+func (history *History) Last(job string) (Run, bool) {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+	if len(history.runs[job]) == 0 {
+		return Run{}, false
+	}
+	return history.runs[job][0], true
+}
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler 状态接口
+//
+//	GET /healthz 调度运行中返回 200 ok, 停止后返回 503
+//	GET /jobs    所有任务的状态 (见 Job), JSON
+//	GET /metrics Prometheus 文本格式的指标
+//	POST /jobs/{name}/run 立即运行任务并等待结束, 返回运行记录 (见 Run); ?wait=false 时在后台运行并返回 202
+//
+// 触发任务需要请求头 Authorization: Bearer <令牌> (见 SetToken), 未设置令牌时返回 403, 令牌错误时返回 401;
+// 运行结果对应的状态码: 成功 200, 失败或取消 500, 正在运行 409, 任务不存在 404, 调度已停止 503
+func (scheduler *Scheduler) Handler() http.Handler {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/healthz", func(writer http.ResponseWriter, request *http.Request) {
+		scheduler.lock.Lock()
+		var stopped = scheduler.stopped
+		scheduler.lock.Unlock()
+		if stopped {
+			http.Error(writer, ErrStopped.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprintln(writer, "ok")
+	})
+	mux.HandleFunc("/jobs", func(writer http.ResponseWriter, request *http.Request) {
+		var jobs = scheduler.Jobs()
+		if jobs == nil {
+			jobs = []Job{}
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		var encoder = json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(jobs)
+	})
+	mux.HandleFunc("/jobs/", func(writer http.ResponseWriter, request *http.Request) {
+		var path = strings.TrimPrefix(request.URL.Path, "/jobs/")
+		if !strings.HasSuffix(path, "/run") {
+			http.NotFound(writer, request)
+			return
+		}
+		if request.Method != http.MethodPost {
+			writer.Header().Set("Allow", http.MethodPost)
+			http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !scheduler.authorized(writer, request) {
+			return
+		}
+		var name = strings.TrimSuffix(path, "/run")
+		if request.URL.Query().Get("wait") == "false" {
+			err := scheduler.Trigger(name)
+			if err != nil {
+				http.Error(writer, err.Error(), errorStatus(err))
+				return
+			}
+			writer.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprintln(writer, "triggered")
+			return
+		}
+		run, err := scheduler.Run(name)
+		if run.Status == "" {
+			http.Error(writer, err.Error(), errorStatus(err))
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writer.WriteHeader(errorStatus(err))
+		_ = json.NewEncoder(writer).Encode(run)
+	})
+	mux.HandleFunc("/metrics", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = scheduler.WriteMetrics(writer)
+	})
+	return mux
+}
+
+// authorized 校验触发任务请求的令牌, 失败时返回 401/403
+func (scheduler *Scheduler) authorized(writer http.ResponseWriter, request *http.Request) bool {
+	scheduler.lock.Lock()
+	var token = scheduler.token
+	scheduler.lock.Unlock()
+	if token == "" {
+		http.Error(writer, "trigger disabled: token not configured ("+TokenEnv+")", http.StatusForbidden)
+		return false
+	}
+	var value = request.Header.Get("Authorization")
+	if !strings.HasPrefix(value, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(value, "Bearer ")), []byte(token)) != 1 {
+		writer.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(writer, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// errorStatus 运行异常对应的状态码
+func errorStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRunning):
+		return http.StatusConflict
+	case errors.Is(err, ErrStopped):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Listen 在 address (如 127.0.0.1:9100) 上提供状态接口 (见 Handler), Stop 时关闭; 监听失败时返回异常
+func (scheduler *Scheduler) Listen(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", address, err)
+	}
+	var server = &http.Server{Handler: scheduler.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	scheduler.lock.Lock()
+	scheduler.server = server
+	scheduler.lock.Unlock()
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error(fmt.Sprintf("[Scheduler] %s", err))
+		}
+	}()
+	logger.Info(fmt.Sprintf("[Scheduler] 状态接口: http://%s (/healthz, /jobs, /metrics, POST /jobs/{name}/run)", listener.Addr()))
+	return nil
+}
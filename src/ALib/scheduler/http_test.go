@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(test *testing.T) {
+	test.Setenv(TokenEnv, "")
+	var scheduler = New(nil)
+	err := scheduler.Add("nacos", "0 2 * * *", func(ctx context.Context) error {
+		AddUploaded(ctx, 1024)
+		AddUploaded(ctx, 1024)
+		return nil
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = scheduler.Add(`git"lab`, "0 3 * * *", func(ctx context.Context) error {
+		return errors.New("export failed")
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	scheduler.Start()
+	_, _ = scheduler.Run("nacos")
+	_, _ = scheduler.Run(`git"lab`)
+	_, _ = scheduler.Run(`git"lab`)
+	AddUploaded(context.Background(), 1024)
+
+	var server = httptest.NewServer(scheduler.Handler())
+	defer server.Close()
+	var get = func(path string) (int, string) {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			test.Fatal(err)
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			test.Fatal(err)
+		}
+		return response.StatusCode, string(body)
+	}
+
+	if code, body := get("/healthz"); code != http.StatusOK || body != "ok\n" {
+		test.Fatal(code, body)
+	}
+
+	_, body := get("/jobs")
+	var jobs []Job
+	if err = json.Unmarshal([]byte(body), &jobs); err != nil {
+		test.Fatal(err, body)
+	}
+	if len(jobs) != 2 || jobs[0].Name != `git"lab` || jobs[0].LastError != "export failed" || jobs[0].LastSuccess != nil || jobs[0].Next == nil {
+		test.Fatal(body)
+	}
+	if jobs[1].Last == nil || jobs[1].Last.Status != StatusSuccess || jobs[1].LastSuccess == nil || jobs[1].LastFailure != nil {
+		test.Fatal(body)
+	}
+
+	_, body = get("/metrics")
+	for _, line := range []string{
+		"# TYPE longyuan_job_runs_total counter",
+		`longyuan_job_runs_total{job="nacos",status="success"} 1`,
+		`longyuan_job_runs_total{job="git\"lab",status="failed"} 2`,
+		`longyuan_job_run_duration_seconds_count{job="git\"lab"} 2`,
+		`longyuan_job_uploaded_bytes_total{job="nacos"} 2048`,
+		`longyuan_job_uploaded_bytes_total{job="git\"lab"} 0`,
+		`longyuan_job_last_success_timestamp_seconds{job="git\"lab"} 0`,
+		`longyuan_job_running{job="nacos"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			test.Fatal(line, "\n", body)
+		}
+	}
+	if !strings.Contains(body, fmt.Sprintf(`longyuan_job_last_success_timestamp_seconds{job="nacos"} %d`+"\n", jobs[1].LastSuccess.Unix())) {
+		test.Fatal(body)
+	}
+
+	var token = "Bearer trigger-token"
+	var post = func(path string) (int, string) {
+		request, err := http.NewRequest(http.MethodPost, server.URL+path, nil)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if token != "" {
+			request.Header.Set("Authorization", token)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			test.Fatal(err)
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			test.Fatal(err)
+		}
+		return response.StatusCode, string(body)
+	}
+	// 未设置令牌时不允许触发, 令牌错误时返回 401
+	code, body := post("/jobs/nacos/run")
+	if code != http.StatusForbidden {
+		test.Fatal(code, body)
+	}
+	scheduler.SetToken("trigger-token")
+	for _, token = range []string{"", "Bearer wrong", "trigger-token"} {
+		if code, body = post("/jobs/nacos/run"); code != http.StatusUnauthorized {
+			test.Fatal(token, code, body)
+		}
+	}
+	token = "Bearer trigger-token"
+	code, body = post("/jobs/nacos/run")
+	var run Run
+	if err = json.Unmarshal([]byte(body), &run); code != http.StatusOK || err != nil || run.Status != StatusSuccess {
+		test.Fatal(code, body)
+	}
+	code, body = post("/jobs/git%22lab/run")
+	if err = json.Unmarshal([]byte(body), &run); code != http.StatusInternalServerError || err != nil || run.Error != "export failed" {
+		test.Fatal(code, body)
+	}
+	if code, body = post("/jobs/docker/run"); code != http.StatusNotFound {
+		test.Fatal(code, body)
+	}
+	if code, body = get("/jobs/nacos/run"); code != http.StatusMethodNotAllowed {
+		test.Fatal(code, body)
+	}
+	if code, body = post("/jobs/nacos/run?wait=false"); code != http.StatusAccepted {
+		test.Fatal(code, body)
+	}
+
+	scheduler.Stop(time.Second)
+	if code, _ := get("/healthz"); code != http.StatusServiceUnavailable {
+		test.Fatal(code)
+	}
+	if code, body = post("/jobs/nacos/run"); code != http.StatusServiceUnavailable {
+		test.Fatal(code, body)
+	}
+}
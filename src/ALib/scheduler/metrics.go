@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// metrics 任务的运行指标, 进程重启后重新计数 (除 uploaded 外由 Scheduler.lock 保护)
+type metrics struct {
+	runs          map[Status]int64
+	durationSum   float64
+	durationCount int64
+	lastDuration  float64
+	// uploaded 上传字节数, 任务运行中通过 AddUploaded 累加
+	uploaded int64
+}
+
+// observe 记录一次结束的运行
+func (value *metrics) observe(run Run) {
+	if value.runs == nil {
+		value.runs = map[Status]int64{}
+	}
+	value.runs[run.Status]++
+	if run.Status == StatusSkipped {
+		return
+	}
+	var seconds = run.Duration().Seconds()
+	value.durationSum += seconds
+	value.durationCount++
+	value.lastDuration = seconds
+}
+
+type uploadedKey struct{}
+
+// AddUploaded 累加任务上传的字节数 (/metrics 中的 longyuan_job_uploaded_bytes_total); ctx 不是调度运行的任务时忽略
+func AddUploaded(ctx context.Context, size int64) {
+	if ctx == nil || size <= 0 {
+		return
+	}
+	if uploaded, ok := ctx.Value(uploadedKey{}).(*int64); ok {
+		atomic.AddInt64(uploaded, size)
+	}
+}
+
+// metricStatuses 输出的运行状态 (运行中的任务见 longyuan_job_running)
+var metricStatuses = []Status{StatusSuccess, StatusFailed, StatusSkipped, StatusCancelled}
+
+// WriteMetrics 输出 Prometheus 文本格式的指标
+func (scheduler *Scheduler) WriteMetrics(writer io.Writer) error {
+	var jobs = scheduler.Jobs()
+	scheduler.lock.Lock()
+	var values = map[string]metrics{}
+	for name, item := range scheduler.jobs {
+		var value = item.metrics
+		value.runs = map[Status]int64{}
+		for status, count := range item.metrics.runs {
+			value.runs[status] = count
+		}
+		value.uploaded = atomic.LoadInt64(&item.metrics.uploaded)
+		values[name] = value
+	}
+	scheduler.lock.Unlock()
+
+	var builder strings.Builder
+	var metric = func(name, kind, help string, value func(job Job) []string) {
+		builder.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind))
+		for _, job := range jobs {
+			for _, line := range value(job) {
+				builder.WriteString(line + "\n")
+			}
+		}
+	}
+	var sample = func(name string, labels string, value interface{}) string {
+		return fmt.Sprintf("%s{%s} %v", name, labels, value)
+	}
+	metric("longyuan_job_runs_total", "counter", "Job runs by status since the process started.", func(job Job) []string {
+		var lines []string
+		for _, status := range metricStatuses {
+			lines = append(lines, sample("longyuan_job_runs_total", jobLabel(job.Name)+`,status="`+string(status)+`"`, values[job.Name].runs[status]))
+		}
+		return lines
+	})
+	metric("longyuan_job_run_duration_seconds", "summary", "Duration of finished job runs.", func(job Job) []string {
+		return []string{
+			sample("longyuan_job_run_duration_seconds_sum", jobLabel(job.Name), values[job.Name].durationSum),
+			sample("longyuan_job_run_duration_seconds_count", jobLabel(job.Name), values[job.Name].durationCount),
+		}
+	})
+	metric("longyuan_job_last_run_duration_seconds", "gauge", "Duration of the last finished job run.", func(job Job) []string {
+		return []string{sample("longyuan_job_last_run_duration_seconds", jobLabel(job.Name), values[job.Name].lastDuration)}
+	})
+	metric("longyuan_job_uploaded_bytes_total", "counter", "Bytes uploaded to storage targets since the process started.", func(job Job) []string {
+		return []string{sample("longyuan_job_uploaded_bytes_total", jobLabel(job.Name), values[job.Name].uploaded)}
+	})
+	metric("longyuan_job_running", "gauge", "Whether the job is running.", func(job Job) []string {
+		var running int
+		if job.Running {
+			running = 1
+		}
+		return []string{sample("longyuan_job_running", jobLabel(job.Name), running)}
+	})
+	metric("longyuan_job_last_success_timestamp_seconds", "gauge", "Unix time of the last successful run, 0 if never succeeded.", func(job Job) []string {
+		var timestamp int64
+		if job.LastSuccess != nil {
+			timestamp = job.LastSuccess.Unix()
+		}
+		return []string{sample("longyuan_job_last_success_timestamp_seconds", jobLabel(job.Name), timestamp)}
+	})
+	metric("longyuan_job_last_failure_timestamp_seconds", "gauge", "Unix time of the last failed or cancelled run, 0 if never failed.", func(job Job) []string {
+		var timestamp int64
+		if job.LastFailure != nil {
+			timestamp = job.LastFailure.Unix()
+		}
+		return []string{sample("longyuan_job_last_failure_timestamp_seconds", jobLabel(job.Name), timestamp)}
+	})
+	metric("longyuan_job_next_run_timestamp_seconds", "gauge", "Unix time of the next scheduled run, 0 if not scheduled.", func(job Job) []string {
+		var timestamp int64
+		if job.Next != nil {
+			timestamp = job.Next.Unix()
+		}
+		return []string{sample("longyuan_job_next_run_timestamp_seconds", jobLabel(job.Name), timestamp)}
+	})
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// jobLabel job 标签, 转义反斜杠、双引号和换行
+func jobLabel(name string) string {
+	var replacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `job="` + replacer.Replace(name) + `"`
+}
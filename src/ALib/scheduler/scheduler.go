@@ -0,0 +1,309 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/robfig/cron/v3"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Func 任务; ctx 在停止超时后取消, 任务应尽快返回. 任务的日志应使用 logger.FromContext(ctx) (包含 job 字段)
+type Func func(ctx context.Context) error
+
+// ErrRunning 任务正在运行
+var ErrRunning = errors.New("job is already running")
+
+// ErrStopped 调度已停止
+var ErrStopped = errors.New("scheduler stopped")
+
+// ErrNotFound 任务不存在
+var ErrNotFound = errors.New("job not found")
+
+// DefaultStopTimeout 默认停止超时
+const DefaultStopTimeout = time.Minute
+
+// TokenEnv 触发任务接口 (POST /jobs/{name}/run) 令牌的环境变量, 见 SetToken
+const TokenEnv = "LONGYUAN_API_TOKEN"
+
+// Scheduler 调度多个定时任务; 同一任务不会并发运行 (上一次未结束时跳过并记录), 每次运行记录到 History
+type Scheduler struct {
+	cron    *cron.Cron
+	history *History
+	lock    sync.Mutex
+	jobs    map[string]*job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wait    sync.WaitGroup
+	stopped bool
+	server  *http.Server
+	token   string
+}
+
+type job struct {
+	name     string
+	schedule string
+	entry    cron.EntryID
+	run      Func
+	running  bool
+	metrics  metrics
+}
+
+// Job 任务状态
+type Job struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Running  bool   `json:"running"`
+	// Next 下次运行时间, 未启动时为 nil
+	Next *time.Time `json:"next,omitempty"`
+	// Last 最近一次运行记录, 没有运行过时为 nil
+	Last *Run `json:"last,omitempty"`
+	// LastSuccess 最近一次成功的结束时间
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// LastFailure 最近一次失败 (或取消) 的结束时间, LastError 为该次运行的异常
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// New 创建调度; history 为空时使用内存中的运行记录
+func New(history *History) *Scheduler {
+	if history == nil {
+		history, _ = NewHistory(nil, 0)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		cron:    cron.New(),
+		history: history,
+		jobs:    map[string]*job{},
+		ctx:     ctx,
+		cancel:  cancel,
+		token:   os.Getenv(TokenEnv),
+	}
+}
+
+// SetToken 设置触发任务接口的令牌 (默认读取环境变量 LONGYUAN_API_TOKEN); 为空时不允许通过接口触发任务
+func (scheduler *Scheduler) SetToken(token string) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	scheduler.token = token
+}
+
+// Add 添加任务; schedule 为 Cron 表达式, 任务名称不能重复
+func (scheduler *Scheduler) Add(name, schedule string, run Func) error {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	if _, ok := scheduler.jobs[name]; ok {
+		return fmt.Errorf("job %s: duplicate name", name)
+	}
+	entry, err := scheduler.cron.AddFunc(schedule, func() {
+		scheduler.background(name)
+	})
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	scheduler.jobs[name] = &job{name: name, schedule: schedule, entry: entry, run: run}
+	return nil
+}
+
+// Run 立即运行任务并等待结束; 任务正在运行时记录为跳过并返回 ErrRunning, 任务失败时返回任务的异常
+func (scheduler *Scheduler) Run(name string) (Run, error) {
+	scheduler.lock.Lock()
+	item, ok := scheduler.jobs[name]
+	if !ok {
+		scheduler.lock.Unlock()
+		return Run{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if scheduler.stopped {
+		scheduler.lock.Unlock()
+		return Run{}, ErrStopped
+	}
+	var run = Run{Job: name, Start: time.Now(), Status: StatusRunning}
+	var jobLogger = logger.With(logger.Fields{"job": name})
+	if item.running {
+		scheduler.lock.Unlock()
+		run.End = run.Start
+		run.Status = StatusSkipped
+		run.Error = ErrRunning.Error()
+		scheduler.record(run)
+		scheduler.lock.Lock()
+		item.metrics.observe(run)
+		scheduler.lock.Unlock()
+		jobLogger.Warn("[Scheduler] 上一次运行尚未结束, 跳过")
+		return run, ErrRunning
+	}
+	item.running = true
+	scheduler.wait.Add(1)
+	scheduler.lock.Unlock()
+	defer func() {
+		scheduler.lock.Lock()
+		item.running = false
+		scheduler.lock.Unlock()
+		scheduler.wait.Done()
+	}()
+
+	scheduler.record(run)
+	jobLogger.Info("[Scheduler] 开始运行")
+	var ctx = logger.NewContext(context.WithValue(scheduler.ctx, uploadedKey{}, &item.metrics.uploaded), jobLogger)
+	err := scheduler.call(item, ctx)
+	run.End = time.Now()
+	switch {
+	case err == nil:
+		run.Status = StatusSuccess
+	case scheduler.ctx.Err() != nil:
+		run.Status = StatusCancelled
+		run.Error = err.Error()
+	default:
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	}
+	scheduler.record(run)
+	scheduler.lock.Lock()
+	item.metrics.observe(run)
+	scheduler.lock.Unlock()
+	if err != nil {
+		jobLogger.Error("[Scheduler] %s (%s) %s", run.Status, run.Duration().Round(time.Second), err)
+	} else {
+		jobLogger.Success("[Scheduler] %s (%s)", run.Status, run.Duration().Round(time.Second))
+	}
+	return run, err
+}
+
+// Trigger 在后台立即运行任务 (见 Run), 不等待任务结束; 任务不存在或调度已停止时返回异常
+func (scheduler *Scheduler) Trigger(name string) error {
+	scheduler.lock.Lock()
+	_, ok := scheduler.jobs[name]
+	var stopped = scheduler.stopped
+	scheduler.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if stopped {
+		return ErrStopped
+	}
+	go scheduler.background(name)
+	return nil
+}
+
+// background 运行任务; 任务的结果由 Run 输出, 这里只输出未能运行的异常
+func (scheduler *Scheduler) background(name string) {
+	run, err := scheduler.Run(name)
+	if err != nil && run.Status == "" {
+		logger.With(logger.Fields{"job": name}).Error("[Scheduler] %s", err)
+	}
+}
+
+// call 运行任务, panic 作为任务异常
+func (scheduler *Scheduler) call(item *job, ctx context.Context) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = fmt.Errorf("panic: %v", value)
+		}
+	}()
+	return item.run(ctx)
+}
+
+// record 保存运行记录, 失败只输出日志
+func (scheduler *Scheduler) record(run Run) {
+	err := scheduler.history.save(run)
+	if err != nil {
+		logger.With(logger.Fields{"job": run.Job}).Error("[Scheduler] save history: %s", err)
+	}
+}
+
+// History 运行记录
+func (scheduler *Scheduler) History() *History {
+	return scheduler.history
+}
+
+// Jobs 所有任务的状态, 按名称排序
+func (scheduler *Scheduler) Jobs() []Job {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	var jobs []Job
+	for _, item := range scheduler.jobs {
+		var value = Job{
+			Name:     item.name,
+			Schedule: item.schedule,
+			Running:  item.running,
+		}
+		if next := scheduler.cron.Entry(item.entry).Next; !next.IsZero() {
+			value.Next = &next
+		}
+		for index, run := range scheduler.history.List(item.name) {
+			var run = run
+			if index == 0 {
+				value.Last = &run
+			}
+			switch run.Status {
+			case StatusSuccess:
+				if value.LastSuccess == nil {
+					value.LastSuccess = &run.End
+				}
+			case StatusFailed, StatusCancelled:
+				if value.LastFailure == nil {
+					value.LastFailure = &run.End
+					value.LastError = run.Error
+				}
+			}
+			if value.LastSuccess != nil && value.LastFailure != nil {
+				break
+			}
+		}
+		jobs = append(jobs, value)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+	return jobs
+}
+
+// Start 开始调度
+func (scheduler *Scheduler) Start() {
+	scheduler.cron.Start()
+}
+
+// Stop 停止调度; 不再运行新的任务, 等待运行中的任务结束, 超过 timeout 后取消任务 (ctx) 并等待任务返回, 最后关闭状态接口
+func (scheduler *Scheduler) Stop(timeout time.Duration) {
+	scheduler.lock.Lock()
+	scheduler.stopped = true
+	var server = scheduler.server
+	scheduler.lock.Unlock()
+	// 不等待 cron 中运行的任务, 由下面的超时控制
+	scheduler.cron.Stop()
+
+	var done = make(chan struct{})
+	go func() {
+		scheduler.wait.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logger.Warn(fmt.Sprintf("[Scheduler] 等待超过 %s, 取消运行中的任务", timeout))
+		scheduler.cancel()
+		<-done
+	}
+	scheduler.cancel()
+	if server != nil {
+		_ = server.Close()
+	}
+}
+
+// Serve 开始调度并阻塞, 收到 SIGINT 或 SIGTERM 时停止 (见 Stop)
+func (scheduler *Scheduler) Serve(timeout time.Duration) {
+	scheduler.Start()
+	var signals = make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	value := <-signals
+	logger.Warn(fmt.Sprintf("[Scheduler] 收到 %s, 等待运行中的任务结束 (最多 %s)", value, timeout))
+	scheduler.Stop(timeout)
+	logger.Info("[Scheduler] 已停止")
+}
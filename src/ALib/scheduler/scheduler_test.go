@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/longyuan/lib.v3/state"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRun(test *testing.T) {
+	var statePath = filepath.Join(test.TempDir(), "history.json")
+	store, err := state.Open(statePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	history, err := NewHistory(store, 2)
+	if err != nil {
+		test.Fatal(err)
+	}
+	var scheduler = New(history)
+	var started = make(chan struct{})
+	var release = make(chan struct{})
+	err = scheduler.Add("nacos", "0 2 * * *", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if err = scheduler.Add("nacos", "0 3 * * *", nil); err == nil {
+		test.Fatal("duplicate job error expected")
+	}
+	err = scheduler.Add("gitlab", "0 3 * * *", func(ctx context.Context) error {
+		return errors.New("export failed")
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	var done = make(chan Run)
+	go func() {
+		run, _ := scheduler.Run("nacos")
+		done <- run
+	}()
+	<-started
+	if jobs := scheduler.Jobs(); len(jobs) != 2 || jobs[1].Name != "nacos" || !jobs[1].Running || jobs[1].Last.Status != StatusRunning {
+		test.Fatal(jobs)
+	}
+	if run, err := scheduler.Run("nacos"); !errors.Is(err, ErrRunning) || run.Status != StatusSkipped {
+		test.Fatal(run, err)
+	}
+	close(release)
+	if run := <-done; run.Status != StatusSuccess || run.End.Before(run.Start) {
+		test.Fatal(run)
+	}
+	if run, err := scheduler.Run("gitlab"); err == nil || run.Status != StatusFailed || run.Error != "export failed" {
+		test.Fatal(run, err)
+	}
+
+	// 重新读取状态文件
+	store, err = state.Open(statePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	history, err = NewHistory(store, 2)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if runs := history.List("nacos"); len(runs) != 2 || runs[0].Status != StatusSkipped || runs[1].Status != StatusSuccess {
+		test.Fatal(runs)
+	}
+}
+
+func TestStop(test *testing.T) {
+	var scheduler = New(nil)
+	var started = make(chan struct{})
+	err := scheduler.Add("kubernetes", "0 2 * * *", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	scheduler.Start()
+	go func() {
+		_, _ = scheduler.Run("kubernetes")
+	}()
+	<-started
+	var begin = time.Now()
+	scheduler.Stop(100 * time.Millisecond)
+	if time.Since(begin) < 100*time.Millisecond {
+		test.Fatal("stop should wait for running job")
+	}
+	if run, ok := scheduler.History().Last("kubernetes"); !ok || run.Status != StatusCancelled {
+		test.Fatal(run)
+	}
+	if _, err = scheduler.Run("kubernetes"); !errors.Is(err, ErrStopped) {
+		test.Fatal(err)
+	}
+}
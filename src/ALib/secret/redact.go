@@ -0,0 +1,87 @@
+package secret
+
+import (
+	"github.com/fatih/color"
+	"github.com/longyuan/lib.v3/logger"
+	"io"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask 脱敏后的替换内容
+const Mask = "******"
+
+// minLength 登记的最短密钥, 过短的值替换会误伤正常输出
+const minLength = 4
+
+var (
+	secretsLock sync.RWMutex
+	secrets     []string
+	installOnce sync.Once
+)
+
+// Register 登记密钥, 之后 color, log 和 logger 的输出中出现的密钥替换为 ******; 同时登记 URL 编码后的形式
+func Register(values ...string) {
+	secretsLock.Lock()
+	for _, value := range values {
+		for _, item := range []string{value, url.QueryEscape(value), url.PathEscape(value)} {
+			if len(item) < minLength || contains(secrets, item) {
+				continue
+			}
+			secrets = append(secrets, item)
+		}
+	}
+	// 先替换较长的密钥, 避免部分替换
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	secretsLock.Unlock()
+	installOnce.Do(install)
+}
+
+// Redact 替换文本中已登记的密钥
+func Redact(text string) string {
+	secretsLock.RLock()
+	defer secretsLock.RUnlock()
+	for _, value := range secrets {
+		text = strings.ReplaceAll(text, value, Mask)
+	}
+	return text
+}
+
+// Writer 脱敏写入 w; 每次 Write 单独替换, 跨两次写入的密钥不会被替换
+func Writer(w io.Writer) io.Writer {
+	return &redactWriter{w: w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (writer *redactWriter) Write(p []byte) (int, error) {
+	_, err := io.WriteString(writer.w, Redact(string(p)))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// install 替换 color, log 和 logger (包括日志文件) 的输出为脱敏输出
+func install() {
+	color.Output = Writer(color.Output)
+	color.Error = Writer(color.Error)
+	log.SetOutput(Writer(log.Writer()))
+	logger.SetRedact(Redact)
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 引用前缀
+const (
+	// PrefixEnv 环境变量; 如 env:GITLAB_TOKEN
+	PrefixEnv = "env:"
+	// PrefixFile 文件内容 (去掉首尾空白); 如 file:/run/secrets/gitlab_token. file:// 开头的值 (如本地存储 file:///data) 不是引用
+	PrefixFile = "file:"
+	// PrefixVault 本地加密密钥库; 如 vault:gitlab-token
+	PrefixVault = "vault:"
+)
+
+var (
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	vaultNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-/]+$`)
+)
+
+// IsReference 是否为密钥引用 (env:NAME, file:PATH, vault:NAME)
+func IsReference(value string) bool {
+	switch {
+	case strings.HasPrefix(value, PrefixEnv):
+		return envNamePattern.MatchString(strings.TrimPrefix(value, PrefixEnv))
+	case strings.HasPrefix(value, PrefixFile):
+		var filePath = strings.TrimPrefix(value, PrefixFile)
+		return filePath != "" && !strings.HasPrefix(filePath, "//")
+	case strings.HasPrefix(value, PrefixVault):
+		return vaultNamePattern.MatchString(strings.TrimPrefix(value, PrefixVault))
+	}
+	return false
+}
+
+// Resolve 解析密钥引用并登记解析结果 (日志输出时脱敏); 不是引用时原样返回
+func Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	var result string
+	switch {
+	case strings.HasPrefix(value, PrefixEnv):
+		var name = strings.TrimPrefix(value, PrefixEnv)
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret %s: environment variable not set", value)
+		}
+		result = env
+	case strings.HasPrefix(value, PrefixFile):
+		fileBytes, err := os.ReadFile(strings.TrimPrefix(value, PrefixFile))
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %w", value, err)
+		}
+		result = strings.TrimSpace(string(fileBytes))
+	default:
+		vault, err := DefaultVault()
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %w", value, err)
+		}
+		var ok bool
+		result, ok = vault.Get(strings.TrimPrefix(value, PrefixVault))
+		if !ok {
+			return "", fmt.Errorf("secret %s: not found in vault %s", value, vault.path)
+		}
+	}
+	Register(result)
+	return result, nil
+}
+
+// ResolveAll 解析多个值中的密钥引用
+func ResolveAll(values []string) ([]string, error) {
+	var results = make([]string, 0, len(values))
+	for _, value := range values {
+		result, err := Resolve(value)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+var (
+	defaultVault     *Vault
+	defaultVaultErr  error
+	defaultVaultOnce sync.Once
+)
+
+// DefaultVault 默认密钥库; 路径为环境变量 LONGYUAN_VAULT (默认 ~/.longyuan/vault.age), 密钥为 LONGYUAN_VAULT_KEY (pass:口令、age 私钥或私钥文件)
+func DefaultVault() (*Vault, error) {
+	defaultVaultOnce.Do(func() {
+		defaultVault, defaultVaultErr = OpenVault(VaultPath(), os.Getenv(VaultKeyEnv))
+	})
+	return defaultVault, defaultVaultErr
+}
@@ -0,0 +1,174 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"filippo.io/age"
+	"fmt"
+	"github.com/longyuan/lib.v3/encrypt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 默认密钥库的环境变量
+const (
+	// VaultPathEnv 密钥库文件路径
+	VaultPathEnv = "LONGYUAN_VAULT"
+	// VaultKeyEnv 密钥库密钥 (pass:口令、age 私钥 AGE-SECRET-KEY-1... 或私钥文件)
+	VaultKeyEnv = "LONGYUAN_VAULT_KEY"
+)
+
+// VaultPath 默认密钥库路径
+func VaultPath() string {
+	if value := os.Getenv(VaultPathEnv); value != "" {
+		return value
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "vault.age"
+	}
+	return filepath.Join(home, ".longyuan", "vault.age")
+}
+
+// Vault 本地加密密钥库; 名称到密钥的映射保存为 age 加密的 JSON 文件, 写入时先写临时文件再替换
+type Vault struct {
+	path string
+	key  string
+	lock sync.Mutex
+	data map[string]string
+}
+
+// OpenVault 打开密钥库, 文件不存在时为空; key 为 pass:口令、age 私钥或私钥文件
+func OpenVault(path, key string) (*Vault, error) {
+	if strings.TrimSpace(key) == "" {
+		return nil, fmt.Errorf("vault key required (%s)", VaultKeyEnv)
+	}
+	var vault = Vault{path: path, key: key, data: map[string]string{}}
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &vault, nil
+		}
+		return nil, err
+	}
+	reader, err := encrypt.Reader(bytes.NewReader(fileBytes), []string{key})
+	if err != nil {
+		return nil, fmt.Errorf("vault %s: %w", path, err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("vault %s: %w", path, err)
+	}
+	err = json.Unmarshal(data, &vault.data)
+	if err != nil {
+		return nil, fmt.Errorf("vault %s: %w", path, err)
+	}
+	return &vault, nil
+}
+
+// Path 密钥库文件路径
+func (vault *Vault) Path() string {
+	return vault.path
+}
+
+// Get 读取密钥
+func (vault *Vault) Get(name string) (string, bool) {
+	vault.lock.Lock()
+	defer vault.lock.Unlock()
+	value, ok := vault.data[name]
+	return value, ok
+}
+
+// Names 所有密钥名称
+func (vault *Vault) Names() []string {
+	vault.lock.Lock()
+	defer vault.lock.Unlock()
+	var names []string
+	for name := range vault.data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set 写入密钥并保存
+func (vault *Vault) Set(name, value string) error {
+	if !vaultNamePattern.MatchString(name) {
+		return fmt.Errorf("vault secret name not supported: %s", name)
+	}
+	vault.lock.Lock()
+	defer vault.lock.Unlock()
+	vault.data[name] = value
+	return vault.save()
+}
+
+// Delete 删除密钥并保存; 不存在时返回 false
+func (vault *Vault) Delete(name string) (bool, error) {
+	vault.lock.Lock()
+	defer vault.lock.Unlock()
+	if _, ok := vault.data[name]; !ok {
+		return false, nil
+	}
+	delete(vault.data, name)
+	return true, vault.save()
+}
+
+func (vault *Vault) save() error {
+	recipients, err := vaultRecipients(vault.key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(vault.data)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(vault.path), 0700)
+	if err != nil {
+		return err
+	}
+	var tempPath = vault.path + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	writer, err := encrypt.Writer(file, recipients)
+	if err == nil {
+		_, err = writer.Write(data)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return os.Rename(tempPath, vault.path)
+}
+
+// vaultRecipients 密钥库的加密接收者; 口令直接使用, 私钥使用对应的公钥
+func vaultRecipients(key string) ([]string, error) {
+	if strings.HasPrefix(strings.TrimSpace(key), "pass:") {
+		return []string{strings.TrimSpace(key)}, nil
+	}
+	identities, err := encrypt.ParseIdentities([]string{key})
+	if err != nil {
+		return nil, err
+	}
+	var recipients []string
+	for _, identity := range identities {
+		if item, ok := identity.(*age.X25519Identity); ok {
+			recipients = append(recipients, item.Recipient().String())
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("vault key: no age identity found")
+	}
+	return recipients, nil
+}
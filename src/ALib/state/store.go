@@ -0,0 +1,154 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store 本地持久化存储; 按 bucket 分组的 Key/Value, 整体保存为一个 JSON 文件, 写入时先写临时文件再替换
+type Store struct {
+	path string
+	lock sync.Mutex
+	data map[string]map[string]json.RawMessage
+}
+
+// Open 打开存储文件, 文件不存在时创建
+func Open(path string) (*Store, error) {
+	var store = Store{path: path, data: map[string]map[string]json.RawMessage{}}
+	fileBytes, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(fileBytes) > 0 {
+		err = json.Unmarshal(fileBytes, &store.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &store, nil
+}
+
+// Get 读取 value; 不存在时返回 false
+func (store *Store) Get(bucket, key string, value any) (bool, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	data, ok := store.data[bucket][key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, value)
+}
+
+// Put 写入 value 并保存
+func (store *Store) Put(bucket, key string, value any) error {
+	return store.Update(func(tx *Tx) error {
+		return tx.Put(bucket, key, value)
+	})
+}
+
+// Delete 删除 key 并保存
+func (store *Store) Delete(bucket, key string) error {
+	return store.Update(func(tx *Tx) error {
+		tx.Delete(bucket, key)
+		return nil
+	})
+}
+
+// Keys bucket 下所有 key (已排序)
+func (store *Store) Keys(bucket string) []string {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	var keys []string
+	for key := range store.data[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Tx 批量修改, 在 Update 结束时一次保存
+type Tx struct {
+	store *Store
+}
+
+// Get 读取 value; 不存在时返回 false
+func (tx *Tx) Get(bucket, key string, value any) (bool, error) {
+	data, ok := tx.store.data[bucket][key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, value)
+}
+
+// Put 写入 value
+func (tx *Tx) Put(bucket, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, ok := tx.store.data[bucket]; !ok {
+		tx.store.data[bucket] = map[string]json.RawMessage{}
+	}
+	tx.store.data[bucket][key] = data
+	return nil
+}
+
+// Delete 删除 key
+func (tx *Tx) Delete(bucket, key string) {
+	delete(tx.store.data[bucket], key)
+}
+
+// Keys bucket 下所有 key (已排序)
+func (tx *Tx) Keys(bucket string) []string {
+	var keys []string
+	for key := range tx.store.data[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Update 批量修改并保存; fn 返回异常时丢弃本次修改
+func (store *Store) Update(fn func(tx *Tx) error) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	var backup = map[string]map[string]json.RawMessage{}
+	for bucket, values := range store.data {
+		backup[bucket] = map[string]json.RawMessage{}
+		for key, value := range values {
+			backup[bucket][key] = value
+		}
+	}
+	err := fn(&Tx{store: store})
+	if err == nil {
+		err = store.save()
+	}
+	if err != nil {
+		store.data = backup
+		return err
+	}
+	return nil
+}
+
+// save 保存到文件
+func (store *Store) save() error {
+	data, err := json.MarshalIndent(store.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	var temp = store.path + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, store.path)
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreReopen(test *testing.T) {
+	var storePath = filepath.Join(test.TempDir(), "data", "state.json")
+	store, err := Open(storePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = store.Update(func(tx *Tx) error {
+		err := tx.Put("alerts", "domain|SSL|b.com", map[string]string{"state": "expiring"})
+		if err != nil {
+			return err
+		}
+		return tx.Put("alerts", "domain|SSL|a.com", map[string]string{"state": "ok"})
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if err = store.Put("digest", "domain", "2023-07-26"); err != nil {
+		test.Fatal(err)
+	}
+
+	// 重新打开存储, 数据保留
+	store, err = Open(storePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if keys := store.Keys("alerts"); strings.Join(keys, ",") != "domain|SSL|a.com,domain|SSL|b.com" {
+		test.Fatal(keys)
+	}
+	var record map[string]string
+	exist, err := store.Get("alerts", "domain|SSL|b.com", &record)
+	if err != nil || !exist || record["state"] != "expiring" {
+		test.Fatal(exist, record, err)
+	}
+	var digest string
+	if exist, err = store.Get("digest", "domain", &digest); err != nil || !exist || digest != "2023-07-26" {
+		test.Fatal(exist, digest, err)
+	}
+	if exist, err = store.Get("alerts", "missing", &record); err != nil || exist {
+		test.Fatal(exist, err)
+	}
+
+	if err = store.Delete("alerts", "domain|SSL|a.com"); err != nil {
+		test.Fatal(err)
+	}
+	store, err = Open(storePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if keys := store.Keys("alerts"); len(keys) != 1 {
+		test.Fatal(keys)
+	}
+}
+
+func TestStoreUpdateRollback(test *testing.T) {
+	var storePath = filepath.Join(test.TempDir(), "state.json")
+	store, err := Open(storePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if err = store.Put("alerts", "a", "expiring"); err != nil {
+		test.Fatal(err)
+	}
+	err = store.Update(func(tx *Tx) error {
+		err := tx.Put("alerts", "a", "ok")
+		if err != nil {
+			return err
+		}
+		tx.Delete("alerts", "a")
+		_ = tx.Put("alerts", "b", "failing")
+		return errors.New("push failed")
+	})
+	if err == nil {
+		test.Fatal("error expected")
+	}
+	// 失败时内存和文件中的数据都不变
+	for _, item := range []*Store{store, reopen(test, storePath)} {
+		var value string
+		exist, err := item.Get("alerts", "a", &value)
+		if err != nil || !exist || value != "expiring" || len(item.Keys("alerts")) != 1 {
+			test.Fatal(exist, value, item.Keys("alerts"), err)
+		}
+	}
+}
+
+func reopen(test *testing.T, storePath string) *Store {
+	store, err := Open(storePath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	return store
+}
@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/longyuan/docker.v3/console"
+	"github.com/longyuan/lib.v3/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -15,33 +15,33 @@ func Pipeline() []*cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			inputAccessKeyId, err := cmd.Flags().GetString("iS3.ak")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			inputSecretKeyId, err := cmd.Flags().GetString("iS3.sk")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			inputDockerTcp, err := cmd.Flags().GetString("iDocker.tcp")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 
 			writeAccessKeyId, err := cmd.Flags().GetString("oS3.ak")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			writeSecretKeyId, err := cmd.Flags().GetString("oS3.sk")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			writeDockerTcp, err := cmd.Flags().GetString("oDocker.tcp")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 
@@ -72,7 +72,7 @@ func Pipeline() []*cobra.Command {
 			}
 			err = console.Pipeline(read, write)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
@@ -98,32 +98,32 @@ func Console() []*cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			username, err := cmd.Flags().GetString("username")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			password, err := cmd.Flags().GetString("password")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			tcp, err := cmd.Flags().GetString("tcp")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			image, err := cmd.Flags().GetString("image")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if image == "" {
-				color.Red(fmt.Sprint("image is null ?"))
+				logger.Error(fmt.Sprint("image is null ?"))
 				return
 			}
 
 			err = console.Push(tcp, username, password, image)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
@@ -140,22 +140,22 @@ func Console() []*cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			tcp, err := cmd.Flags().GetString("tcp")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			image, err := cmd.Flags().GetString("image")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if image == "" {
-				color.Red(fmt.Sprint("image is null ?"))
+				logger.Error(fmt.Sprint("image is null ?"))
 				return
 			}
 
 			err = console.Remove(tcp, image)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
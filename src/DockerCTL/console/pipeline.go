@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/longyuan/docker.v3/client"
 	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
 	"os"
 	"path"
 )
@@ -127,7 +128,7 @@ func dockerToS3(input Read, write Write) error {
 			return err
 		}
 	}
-	fmt.Println(dockerClient)
-	fmt.Println(writeS3)
+	logger.Debug(fmt.Sprint(dockerClient))
+	logger.Debug(fmt.Sprint(writeS3))
 	return nil
 }
@@ -6,7 +6,6 @@ replace github.com/longyuan/lib.v3 => ../ALib
 
 require (
 	github.com/docker/docker v24.0.4+incompatible
-	github.com/fatih/color v1.15.0
 	github.com/longyuan/lib.v3 v0.0.0
 	github.com/spf13/cobra v1.7.0
 )
@@ -16,6 +15,7 @@ require (
 	github.com/docker/distribution v2.8.2+incompatible // indirect
 	github.com/docker/go-connections v0.4.0 // indirect
 	github.com/docker/go-units v0.5.0 // indirect
+	github.com/fatih/color v1.15.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/longyuan/docker.v3/cmd"
+	"github.com/longyuan/lib.v3/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +14,7 @@ func main() {
 	for _, it := range cmd.Console() {
 		rootCmd.AddCommand(it)
 	}
+	logger.AddFlags(rootCmd, "docker")
 	err := rootCmd.Execute()
 	if err != nil {
 		return
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
@@ -88,7 +90,20 @@ func (cert *X509Certificate) Print() {
 	}
 	color.White(fmt.Sprint("Signature:", cert.Signature))
 	color.White(fmt.Sprint("Public Key Algorithm:", cert.PublicKeyAlgorithm.String()))
-	color.White(fmt.Sprint("Public Key Size (bits):", cert.PublicKey.(*rsa.PublicKey).N.BitLen()))
+	color.White(fmt.Sprint("Public Key Size (bits):", cert.PublicKeySize()))
+}
+
+// PublicKeySize 公钥长度 (bits); 不支持的算法为 0.
+func (cert *X509Certificate) PublicKeySize() int {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
 }
 
 // NotAfterDateParse 解析证书到期时间; 过期天数, 危险级别 0.无危险 1.即将过期 2.已过期, 提示语.
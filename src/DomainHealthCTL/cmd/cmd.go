@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/fatih/color"
 	console "github.com/longyuan/domain.v3/console"
+	"github.com/longyuan/lib.v3/config"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -16,14 +18,21 @@ func Cmd() []*cobra.Command {
 			if len(args) <= 0 {
 				return
 			}
-			err := console.SSL(args[0])
+			output, err := cmd.Flags().GetString("output")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.SSL(args[0], output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
 	}
 
+	sslCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
+
 	var whoisCmd = &cobra.Command{
 		Use:     "whois",
 		Short:   "Host Whois Info",
@@ -31,79 +40,78 @@ func Cmd() []*cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			original, err := cmd.Flags().GetString("original")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if len(args) <= 0 {
 				return
 			}
-			err = console.Whois(args[0], original != "false")
+			err = console.Whois(args[0], original != "false", output)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
 	}
 	whoisCmd.Flags().StringP("original", "o", "false", "original information")
+	whoisCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
 
 	var scanCmd = &cobra.Command{
 		Use:     "scan",
 		Short:   "Scan Config",
-		Example: "scan ./domain.txt",
+		Example: "scan ./domain.txt --output csv",
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) <= 0 {
 				return
 			}
-			err := console.Scan(args[0])
+			output, err := cmd.Flags().GetString("output")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Scan(args[0], output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
 	}
 
+	scanCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
+
 	var cronCmd = &cobra.Command{
 		Use:     "cron",
 		Short:   "Cron Job",
-		Example: "cron -c domain.txt -n ",
+		Example: "cron -j domain-job.yaml",
 		Run: func(cmd *cobra.Command, args []string) {
-			config, err := cmd.Flags().GetString("config")
+			job, err := config.CronJob(cmd, config.SourceDomain)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
-				return
-			}
-			if config == "" {
-				color.Red("Not Set config value ?")
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			cron, err := cmd.Flags().GetString("cron")
+			listen, err := cmd.Flags().GetString("listen")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if cron == "" {
-				color.Red("Not Set cron value ?")
-				return
-			}
-			notice, err := cmd.Flags().GetString("notice")
+			runNow, err := cmd.Flags().GetBool("run-now")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
-				return
-			}
-			if notice == "" {
-				color.Red("Not Set notice value ?")
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			err = console.CronJob(config, cron, notice)
+			err = console.CronJob(job, listen, runNow)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
 	}
-	cronCmd.Flags().StringP("config", "c", "", "Domain Config")
-	cronCmd.Flags().String("cron", "", "Cron")
-	cronCmd.Flags().StringP("notice", "n", "", "Notice Config")
+	config.AddCronFlags(cronCmd, config.SourceDomain)
 
 	return []*cobra.Command{
 		sslCmd,
@@ -1,20 +1,47 @@
 package console
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/longyuan/domain.v3/client"
+	"github.com/longyuan/lib.v3/config"
 	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
 	"github.com/longyuan/lib.v3/message"
-	"github.com/robfig/cron/v3"
-	"math"
+	"github.com/longyuan/lib.v3/scheduler"
+	"github.com/longyuan/lib.v3/state"
+	"github.com/longyuan/lib.v3/times"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 )
 
-func Whois(host string, original bool) error {
+// whoisRecord Whois 信息 (table 以外的输出格式)
+type whoisRecord struct {
+	DomainName                 string    `json:"domainName" title:"DomainName"`
+	RegistryDomainID           string    `json:"registryDomainId" title:"RegistryDomainID"`
+	RegistrarURL               string    `json:"registrarUrl" title:"RegistrarURL"`
+	UpdatedDate                time.Time `json:"updatedDate" title:"UpdatedDate"`
+	CreationDate               time.Time `json:"creationDate" title:"CreationDate"`
+	RegistryExpiryDate         time.Time `json:"registryExpiryDate" title:"RegistryExpiryDate"`
+	Days                       int       `json:"days" title:"RemainingDays"`
+	Registrar                  string    `json:"registrar" title:"Registrar"`
+	RegistrarIANAID            string    `json:"registrarIanaId" title:"RegistrarIANAID"`
+	RegistrarAbuseContactEmail string    `json:"registrarAbuseContactEmail" title:"RegistrarAbuseContactEmail"`
+	RegistrarAbuseContactPhone string    `json:"registrarAbuseContactPhone" title:"RegistrarAbuseContactPhone"`
+	DomainStatus               []string  `json:"domainStatus" title:"DomainStatus"`
+	NameServer                 []string  `json:"nameServer" title:"NameServer"`
+	DNSSEC                     string    `json:"dnssec" title:"DNSSEC"`
+}
+
+// Whois 查询域名 Whois; original 时输出原始信息, 否则按 output 格式 (table|json|yaml|csv|markdown) 输出, table 为着色的详细信息
+func Whois(host string, original bool, output string) error {
+	output, err := ctl.ParseOutput(output)
+	if err != nil {
+		return err
+	}
 	domain, err := client.ParseDomain(host)
 	if err != nil {
 		return err
@@ -25,23 +52,82 @@ func Whois(host string, original bool) error {
 	}
 	if original {
 		color.White(strings.Join(context, "\n"))
-	} else {
-		whoisInfo, err := client.ParseWhoisInfo(context)
-		if err != nil {
-			return err
-		}
+		return nil
+	}
+	whoisInfo, err := client.ParseWhoisInfo(context)
+	if err != nil {
+		return err
+	}
+	if output == ctl.OutputTable {
 		whoisInfo.Println()
+		return nil
 	}
-	return nil
+	days, _, _ := whoisInfo.RegistryExpiryDateParse()
+	return ctl.Print(output, whoisRecord{
+		DomainName:                 whoisInfo.DomainName,
+		RegistryDomainID:           whoisInfo.RegistryDomainID,
+		RegistrarURL:               whoisInfo.RegistrarURL,
+		UpdatedDate:                whoisInfo.UpdatedDate,
+		CreationDate:               whoisInfo.CreationDate,
+		RegistryExpiryDate:         whoisInfo.RegistryExpiryDate,
+		Days:                       days,
+		Registrar:                  whoisInfo.Registrar,
+		RegistrarIANAID:            whoisInfo.RegistrarIANAID,
+		RegistrarAbuseContactEmail: whoisInfo.RegistrarAbuseContactEmail,
+		RegistrarAbuseContactPhone: whoisInfo.RegistrarAbuseContactPhone,
+		DomainStatus:               whoisInfo.DomainStatus,
+		NameServer:                 whoisInfo.NameServer,
+		DNSSEC:                     whoisInfo.DNSSEC,
+	})
+}
+
+// sslRecord 证书信息 (table 以外的输出格式)
+type sslRecord struct {
+	Host                  string    `json:"host" title:"Host"`
+	Subject               string    `json:"subject" title:"Subject"`
+	Issuer                string    `json:"issuer" title:"Issuer"`
+	SerialNumber          string    `json:"serialNumber" title:"Serial Number"`
+	NotBefore             time.Time `json:"notBefore" title:"Not Before"`
+	NotAfter              time.Time `json:"notAfter" title:"Not After"`
+	Days                  int       `json:"days" title:"Remaining Days"`
+	SignatureAlgorithm    string    `json:"signatureAlgorithm" title:"Signature Algorithm"`
+	DNSNames              []string  `json:"dnsNames" title:"Subject Alternative Names"`
+	IsCA                  bool      `json:"isCA" title:"Is CA"`
+	IssuingCertificateURL []string  `json:"issuingCertificateUrl" title:"Authority Information Access (AIA)"`
+	PublicKeyAlgorithm    string    `json:"publicKeyAlgorithm" title:"Public Key Algorithm"`
+	PublicKeySize         int       `json:"publicKeySize" title:"Public Key Size (bits)"`
 }
 
-func SSL(host string) error {
+// SSL 查询证书; 按 output 格式 (table|json|yaml|csv|markdown) 输出, table 为着色的详细信息
+func SSL(host, output string) error {
+	output, err := ctl.ParseOutput(output)
+	if err != nil {
+		return err
+	}
 	cert, err := client.SSL(host)
 	if err != nil {
 		return err
 	}
-	cert.Print()
-	return nil
+	if output == ctl.OutputTable {
+		cert.Print()
+		return nil
+	}
+	days, _, _ := cert.NotAfterDateParse()
+	return ctl.Print(output, sslRecord{
+		Host:                  host,
+		Subject:               cert.Subject.CommonName,
+		Issuer:                cert.Issuer.CommonName,
+		SerialNumber:          cert.SerialNumber.String(),
+		NotBefore:             times.In(cert.NotBefore),
+		NotAfter:              times.In(cert.NotAfter),
+		Days:                  days,
+		SignatureAlgorithm:    cert.SignatureAlgorithm.String(),
+		DNSNames:              cert.DNSNames,
+		IsCA:                  cert.IsCA,
+		IssuingCertificateURL: cert.IssuingCertificateURL,
+		PublicKeyAlgorithm:    cert.PublicKeyAlgorithm.String(),
+		PublicKeySize:         cert.PublicKeySize(),
+	})
 }
 
 type DomainScan struct {
@@ -65,219 +151,264 @@ func (domain *DomainScan) whoisDays() float64 {
 	return whoisDuration.Hours() / 24
 }
 
-func (domain *DomainScan) sslDaysContext() string {
-	sslDays := domain.sslDays()
-	if sslDays <= -106751 {
-		return "查询失败: " + domain.message
-	} else if sslDays < 0 {
-		return "已过期: " + strconv.Itoa(int(math.Abs(sslDays))) + "天"
-	} else if sslDays < 15 {
-		return "即将过期: " + strconv.Itoa(int(math.Abs(sslDays))) + "天"
-	} else {
-		return "剩余: " + strconv.Itoa(int(math.Abs(sslDays))) + "天"
+// sslEvent SSL 检查事件, 证书即将过期 (15天) 为 LevelWarning
+func (domain *DomainScan) sslEvent() message.Event {
+	if domain.sslAfter.IsZero() {
+		return message.NewEvent(message.SourceDomain, domain.domain, "SSL", message.LevelCritical, domain.err())
 	}
+	return message.ExpiryEvent(message.SourceDomain, domain.domain, "SSL", int(domain.sslDays()), 15)
 }
 
-func (domain *DomainScan) whoisDaysContext() string {
-	whoisDays := domain.whoisDays()
-	if whoisDays <= -106751 {
-		return "查询失败: " + domain.message
-	} else if whoisDays < 0 {
-		return "已过期: " + strconv.Itoa(int(math.Abs(whoisDays))) + "天"
-	} else if whoisDays < 15 {
-		return "即将过期: " + strconv.Itoa(int(math.Abs(whoisDays))) + "天"
-	} else {
-		return "剩余: " + strconv.Itoa(int(math.Abs(whoisDays))) + "天"
+// whoisEvent Whois 检查事件, 域名即将过期 (15天) 为 LevelWarning
+func (domain *DomainScan) whoisEvent() message.Event {
+	if domain.whoisRegistryExpiryDate.IsZero() {
+		return message.NewEvent(message.SourceDomain, domain.domain, "Whois", message.LevelCritical, domain.err())
 	}
+	return message.ExpiryEvent(message.SourceDomain, domain.domain, "Whois", int(domain.whoisDays()), 15)
 }
 
-func Scan(path string) error {
-	file, err := os.ReadFile(path)
+func (domain *DomainScan) err() error {
+	if domain.message == "" {
+		return errors.New("查询失败")
+	}
+	return errors.New(domain.message)
+}
+
+// scanRow 扫描结果; Check 为 Whois 或 SSL, 查询失败时日期为空
+type scanRow struct {
+	Check   string     `json:"check" title:"检查"`
+	Index   int        `json:"index" title:"序号"`
+	Domain  string     `json:"domain" title:"域名"`
+	Created *time.Time `json:"created" title:"创建日期" format:"2006-01-02"`
+	Expires *time.Time `json:"expires" title:"过期日期" format:"2006-01-02"`
+	Days    int        `json:"days" title:"剩余天数"`
+	Error   string     `json:"error" title:"错误消息"`
+}
+
+// Scan 扫描文件中的域名 (每行一个) 的 Whois 和 SSL 证书; output 输出格式 (table|json|yaml|csv|markdown)
+func Scan(path, output string) error {
+	output, err := ctl.ParseOutput(output)
 	if err != nil {
 		return err
 	}
-	var rows = strings.Split(strings.ReplaceAll(string(file), "\r\n", "\n"), "\n")
-	color.Green("Scan Domain ....")
-	var domain = client.Analysis(client.ParseDomains(rows))
+	file, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	var table [][]string
+	var rows = strings.Split(strings.ReplaceAll(string(file), "\r\n", "\n"), "\n")
+	logger.Success("Scan Domain ....")
+	var domain = client.Analysis(client.ParseDomains(rows))
+	var results []scanRow
 	for index, item := range domain {
 		if item.Message != nil {
-			table = append(table, []string{
-				strconv.Itoa(index + 1), item.Name,
-				"",
-				"",
-				"0", *item.Message,
-			})
+			results = append(results, scanRow{Check: "Whois", Index: index + 1, Domain: item.Name, Error: *item.Message})
 		} else {
 			day, _, _ := item.Whois.RegistryExpiryDateParse()
-			table = append(table, []string{
-				strconv.Itoa(index + 1), item.Name,
-				item.Whois.CreationDate.Format("2006-01-02"),
-				item.Whois.RegistryExpiryDate.Format("2006-01-02"),
-				strconv.Itoa(day), "",
-			})
+			var created, expires = item.Whois.CreationDate, item.Whois.RegistryExpiryDate
+			results = append(results, scanRow{Check: "Whois", Index: index + 1, Domain: item.Name, Created: &created, Expires: &expires, Days: day})
 		}
 	}
-	ctl.PrintTable([]string{"序号", "域名", "Whois 创建日期", "Whois 过期日期", "Whois 剩余天数", "错误消息"}, table)
 
-	table = [][]string{}
 	var sslIndex = 0
 	for _, item := range domain {
 		for _, child := range *item.Child {
 			if child.Message != nil {
-				table = append(table, []string{
-					strconv.Itoa(sslIndex + 1), child.Name,
-					"", "", "0", *child.Message,
-				})
+				results = append(results, scanRow{Check: "SSL", Index: sslIndex + 1, Domain: child.Name, Error: *child.Message})
 			} else {
 				day, _, _ := child.SSL.NotAfterDateParse()
-				table = append(table, []string{
-					strconv.Itoa(sslIndex + 1), child.Name,
-					child.SSL.NotBefore.Format("2006-01-02"),
-					child.SSL.NotAfter.Format("2006-01-02"),
-					strconv.Itoa(day), "",
-				})
+				var created, expires = child.SSL.NotBefore, child.SSL.NotAfter
+				results = append(results, scanRow{Check: "SSL", Index: sslIndex + 1, Domain: child.Name, Created: &created, Expires: &expires, Days: day})
 			}
 			sslIndex += 1
 		}
 	}
-	ctl.PrintTable([]string{"序号", "域名", "SSL 创建日期", "SSL 过期日期", "SSL 剩余天数", "错误消息"}, table)
-
-	return nil
+	return ctl.Print(output, results)
 }
 
-func CronJob(configPath, backupCron, noticeConfig string) error {
+// CheckJob 创建检查任务; 检查任务配置中 domain 类型来源的域名 (domains 和 file 中的每行), job.State 状态文件 (为空时每次推送全部结果), job.DigestHour 每日汇总时间 (为空不汇总)
+func CheckJob(job *config.Job) (scheduler.Func, error) {
+	router, err := job.Notice.Router()
+	if err != nil {
+		return nil, err
+	}
+	if router == nil {
+		return nil, errors.New("notice required")
+	}
+	if job.State != "" {
+		store, err := state.Open(job.State)
+		if err != nil {
+			return nil, err
+		}
+		router.Track(message.NewTracker(store))
+	}
+	var digestHour = -1
+	if job.DigestHour != nil {
+		digestHour = *job.DigestHour
+	}
 
-	c := cron.New()
-	_, err := c.AddFunc(backupCron, func() {
-		err := func() error {
-			// 读取文件
-			file, err := os.ReadFile(configPath)
-			if err != nil {
-				return err
+	if job.Notice.Config != "" {
+		logger.Info(fmt.Sprintf("Notice Config: %s", job.Notice.Describe()))
+	}
+	if job.State != "" {
+		logger.Info(fmt.Sprintf("State: %s", job.State))
+	}
+	return func(ctx context.Context) error {
+		var jobLogger = logger.FromContext(ctx)
+		// 读取域名 (每次运行重新读取文件)
+		rows, err := domainRows(job)
+		if err != nil {
+			return err
+		}
+		var domainSSL []DomainScan
+		var domainWhois []DomainScan
+		var scanDomain []string
+		var scanRootDomain []string
+		for _, item := range rows {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
 			}
-			var domainSSL []DomainScan
-			var domainWhois []DomainScan
-			var rows = strings.Split(strings.ReplaceAll(string(file), "\r\n", "\n"), "\n")
-			var scanDomain []string
-			var scanRootDomain []string
-			for _, item := range rows {
-				item = strings.TrimSpace(item)
-				if item == "" {
-					continue
-				}
-				if strings.HasPrefix(item, "#") {
-					continue
-				}
-				scanDomain = append(scanDomain, item)
-				var rootItem = client.WhoisDomainFormat(item)
-				var exist = func() bool {
-					for _, domain := range scanRootDomain {
-						if domain == rootItem {
-							return true
-						}
+			if strings.HasPrefix(item, "#") {
+				continue
+			}
+			scanDomain = append(scanDomain, item)
+			var rootItem = client.WhoisDomainFormat(item)
+			var exist = func() bool {
+				for _, domain := range scanRootDomain {
+					if domain == rootItem {
+						return true
 					}
-					return false
-				}()
-				if !exist {
-					scanRootDomain = append(scanRootDomain, rootItem)
 				}
+				return false
+			}()
+			if !exist {
+				scanRootDomain = append(scanRootDomain, rootItem)
+			}
+		}
+		// SSL
+		var resultSSL []message.Event
+		for _, item := range scanDomain {
+			if err = ctx.Err(); err != nil {
+				return err
 			}
-			// SSL
-			var resultSSL []string
-			for _, item := range scanDomain {
-				color.Green(fmt.Sprintf("[域名] 执行SSL检查: %s", item))
-				var domainScan = DomainScan{domain: item}
+			jobLogger.With(logger.Fields{"host": item}).Success(fmt.Sprintf("[域名] 执行SSL检查: %s", item))
+			var domainScan = DomainScan{domain: item}
 
-				func() {
-					// SSL
-					certificate, err := client.SSL(domainScan.domain)
-					if err != nil {
-						domainScan.message += fmt.Sprint(err)
-						return
-					}
-					domainScan.sslAfter = certificate.NotAfter
-				}()
+			func() {
+				// SSL
+				certificate, err := client.SSL(domainScan.domain)
+				if err != nil {
+					domainScan.message += fmt.Sprint(err)
+					return
+				}
+				domainScan.sslAfter = certificate.NotAfter
+			}()
 
-				domainSSL = append(domainSSL, domainScan)
-			}
-			for _, item := range domainSSL {
-				resultSSL = append(resultSSL, item.domain+" **SSL ( "+item.sslDaysContext()+" )** ")
-			}
+			domainSSL = append(domainSSL, domainScan)
+		}
+		for _, item := range domainSSL {
+			var event = item.sslEvent()
+			event.Labels = map[string]string{"domain": client.WhoisDomainFormat(item.domain)}
+			resultSSL = append(resultSSL, event)
+		}
 
-			// Whois
-			var resultWhois []string
-			for _, item := range scanRootDomain {
-				color.Green(fmt.Sprintf("[域名] 执行Whois检查: %s", item))
-				var domainScan = DomainScan{domain: item}
+		// Whois
+		var resultWhois []message.Event
+		for _, item := range scanRootDomain {
+			jobLogger.With(logger.Fields{"host": item}).Success(fmt.Sprintf("[域名] 执行Whois检查: %s", item))
+			var domainScan = DomainScan{domain: item}
 
-				//func() {
-				//	// Whois
-				//	whois, err := client.DomainWhoisInfo(domainScan.domain)
-				//	if err != nil {
-				//		domainScan.message += fmt.Sprint(err)
-				//		return
-				//	}
-				//	domainScan.whoisRegistryExpiryDate = whois.RegistryExpiryDate
-				//}()
+			//func() {
+			//	// Whois
+			//	whois, err := client.DomainWhoisInfo(domainScan.domain)
+			//	if err != nil {
+			//		domainScan.message += fmt.Sprint(err)
+			//		return
+			//	}
+			//	domainScan.whoisRegistryExpiryDate = whois.RegistryExpiryDate
+			//}()
 
-				domainWhois = append(domainWhois, domainScan)
-			}
-			for _, item := range domainWhois {
-				resultWhois = append(resultWhois, item.domain+" **Whois ( "+item.whoisDaysContext()+" )** ")
-			}
+			domainWhois = append(domainWhois, domainScan)
+		}
+		for _, item := range domainWhois {
+			var event = item.whoisEvent()
+			event.Labels = map[string]string{"domain": item.domain}
+			resultWhois = append(resultWhois, event)
+		}
 
-			if len(resultSSL) > 0 {
-				// 消息通知
-				var content string
-				for _, item := range resultSSL {
-					content += DomainTemplateCPWeChat(item)
-				}
-				err = message.Push(message.DomainType, noticeConfig, "域名证书SSL 检查", content)
-				if err != nil {
-					return err
-				}
+		if len(resultSSL) > 0 {
+			// 消息通知
+			_, err = router.Dispatch("域名证书SSL 检查", resultSSL)
+			if err != nil {
+				return err
 			}
-			if len(resultWhois) > 0 {
-				// 消息通知
-				var content string
-				for _, item := range resultWhois {
-					content += DomainTemplateCPWeChat(item)
-				}
-				err = message.Push(message.DomainType, noticeConfig, "域名Whois 检查", content)
-				if err != nil {
-					return err
-				}
+		}
+		if len(resultWhois) > 0 {
+			// 消息通知
+			_, err = router.Dispatch("域名Whois 检查", resultWhois)
+			if err != nil {
+				return err
 			}
-
-			return nil
-		}()
+		}
+		// 每日汇总
+		_, err = router.Digest("域名检查 每日汇总", message.SourceDomain, digestHour)
 		if err != nil {
-			color.Red(fmt.Sprint(err))
+			return err
 		}
-	})
+
+		return nil
+	}, nil
+}
+
+// CronJob 定时检查 (见 CheckJob); 收到 SIGTERM 时等待运行中的检查结束; runNow 时启动后立即运行一次
+func CronJob(job *config.Job, listen string, runNow bool) error {
+	run, err := CheckJob(job)
 	if err != nil {
 		return err
 	}
-	color.Blue(fmt.Sprintf("Cron (%s) Start Success ...", backupCron))
-	color.Blue(fmt.Sprintf("ConfigPath: %s", configPath))
-	color.Blue(fmt.Sprintf("Notice Config: %s", noticeConfig))
-	c.Start()
-	select {}
+	var name = job.Name
+	if name == "" {
+		name = config.SourceDomain
+	}
+	var jobScheduler = scheduler.New(nil)
+	err = jobScheduler.Add(name, job.Schedule, run)
+	if err != nil {
+		return err
+	}
+	if listen != "" {
+		err = jobScheduler.Listen(listen)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Info(fmt.Sprintf("Cron (%s) Start Success ...", job.Schedule))
+	if runNow {
+		err = jobScheduler.Trigger(name)
+		if err != nil {
+			return err
+		}
+	}
+	jobScheduler.Serve(scheduler.DefaultStopTimeout)
+	return nil
 }
 
-func DomainTemplateCPWeChat(value string) string {
-	if strings.Index(value, "查询失败") > -1 {
-		return "> <font color=\"red\">" + value + "</font>\n"
-	}
-	if strings.Index(value, "已过期") > -1 {
-		return "> <font color=\"red\">" + value + "</font>\n"
+// domainRows 任务配置中 domain 类型来源的域名, 包含来源文件中的每行
+func domainRows(job *config.Job) ([]string, error) {
+	sources, err := job.ListSources(config.SourceDomain)
+	if err != nil {
+		return nil, err
 	}
-	if strings.Index(value, "即将过期") > -1 {
-		return "> <font color=\"warning\">" + value + "</font>\n"
+	var rows []string
+	for _, source := range sources {
+		rows = append(rows, source.Domains...)
+		if source.File == "" {
+			continue
+		}
+		file, err := os.ReadFile(source.File)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, strings.Split(strings.ReplaceAll(string(file), "\r\n", "\n"), "\n")...)
 	}
-	return "> " + value + "\n"
+	return rows, nil
 }
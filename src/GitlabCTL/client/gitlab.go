@@ -1,7 +1,9 @@
 package client
 
 import (
-	"github.com/fatih/color"
+	"context"
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
 	"github.com/xanzy/go-gitlab"
 	"os"
 	"strings"
@@ -10,7 +12,10 @@ import (
 
 const (
 	gitLabExportStatusFinished = "finished"
+	gitLabExportStatusFailed   = "failed"
 	gitlabWaitStatus           = 429
+	// exportRetries 导出状态查询、下载连续失败的最大次数
+	exportRetries = 10
 )
 
 type GitlabClient struct {
@@ -35,19 +40,19 @@ func NewGitlabClient(host, token string) (*GitlabClient, error) {
 }
 
 // Projects 扫描授权下所有项目列表
-func (g *GitlabClient) Projects() ([]*gitlab.Project, error) {
+func (g *GitlabClient) Projects(ctx context.Context) ([]*gitlab.Project, error) {
 	var cursor = 1
 	var allProjects []*gitlab.Project
 	for {
 		options := &gitlab.ListProjectsOptions{}
 		options.OrderBy = gitlab.String("id")
 		options.ListOptions.Page = cursor
-		projects, response, err := g.client.Projects.ListProjects(options)
+		projects, response, err := g.client.Projects.ListProjects(options, gitlab.WithContext(ctx))
 		if err != nil {
 			return nil, err
 		}
 		for _, project := range projects {
-			color.Green("[Gitlab] 项目ID: (%d) 项目路径: %s", project.ID, project.NameWithNamespace)
+			logger.Success("[Gitlab] 项目ID: (%d) 项目路径: %s", project.ID, project.NameWithNamespace)
 		}
 		allProjects = append(allProjects, projects...)
 		if response.NextPage == 0 {
@@ -58,35 +63,53 @@ func (g *GitlabClient) Projects() ([]*gitlab.Project, error) {
 	return allProjects, nil
 }
 
-func (g *GitlabClient) Export(projectId int, backupFile string) error {
-	_, err := g.client.ProjectImportExport.ScheduleExport(projectId, &gitlab.ScheduleExportOptions{})
+// Export 导出项目到 backupFile; 轮询导出状态并下载导出文件, 请求连续失败 exportRetries 次、导出失败或 ctx 取消时返回异常
+func (g *GitlabClient) Export(ctx context.Context, projectId int, backupFile string) error {
+	_, err := g.client.ProjectImportExport.ScheduleExport(projectId, &gitlab.ScheduleExportOptions{}, gitlab.WithContext(ctx))
 	if err != nil {
 		return err
 	}
 	// 查询导出状态 轮训到成功为止
+	var failures int
 	for {
-		status, _, err := g.client.ProjectImportExport.ExportStatus(projectId)
-		// 如果请求异常再次请求
+		status, _, err := g.client.ProjectImportExport.ExportStatus(projectId, gitlab.WithContext(ctx))
 		if err != nil {
-			time.Sleep(time.Second * 1)
+			if failures++; failures >= exportRetries {
+				return fmt.Errorf("export status: %w", err)
+			}
+			// 如果请求异常再次请求
+			if err = sleep(ctx, time.Second); err != nil {
+				return err
+			}
 			continue
 		}
+		failures = 0
 		if status.ExportStatus == gitLabExportStatusFinished {
 			break
 		}
-		time.Sleep(time.Second * 3)
+		if status.ExportStatus == gitLabExportStatusFailed {
+			return fmt.Errorf("export failed: project %d", projectId)
+		}
+		if err = sleep(ctx, 3*time.Second); err != nil {
+			return err
+		}
 	}
 
 	// 下载导出文件
-	for {
-		download, response, err := g.client.ProjectImportExport.ExportDownload(projectId)
-		if response.StatusCode == gitlabWaitStatus {
-			time.Sleep(time.Second * 30)
-			continue
-		}
-		// 如果请求异常再次请求
+	for failures = 0; ; {
+		download, response, err := g.client.ProjectImportExport.ExportDownload(projectId, gitlab.WithContext(ctx))
 		if err != nil {
-			time.Sleep(time.Second * 1)
+			if failures++; failures >= exportRetries {
+				return fmt.Errorf("export download: %w", err)
+			}
+			// 限流时等待 30s, 其它异常再次请求
+			var delay = time.Second
+			if response != nil && response.StatusCode == gitlabWaitStatus {
+				delay = 30 * time.Second
+			}
+			if err = sleep(ctx, delay); err != nil {
+				return err
+			}
 			continue
 		}
 		// 先写入缓存然后在重命名, 防止文件损坏
@@ -96,11 +119,18 @@ func (g *GitlabClient) Export(projectId int, backupFile string) error {
 			return err
 		}
 		// 重命名文件
-		err = os.Rename(cacheFile, backupFile)
-		if err != nil {
-			return err
-		}
-		break
+		return os.Rename(cacheFile, backupFile)
+	}
+}
+
+// sleep 等待 delay, ctx 取消时返回 ctx 的异常
+func sleep(ctx context.Context, delay time.Duration) error {
+	var timer = time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	return nil
 }
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xanzy/go-gitlab"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportServer 模拟导出接口; status 为导出状态
+func exportServer(test *testing.T, status string) *GitlabClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/export"):
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"message":"202 Accepted"}`))
+		case strings.HasSuffix(r.URL.Path, "/export"):
+			_, _ = fmt.Fprintf(w, `{"id":1,"export_status":%q}`, status)
+		default:
+			_, _ = w.Write([]byte("archive"))
+		}
+	}))
+	test.Cleanup(server.Close)
+	git, err := gitlab.NewClient("token", gitlab.WithBaseURL(server.URL+"/api/v4"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	return &GitlabClient{client: git, URL: server.URL}
+}
+
+func TestExport(test *testing.T) {
+	var backupFile = filepath.Join(test.TempDir(), "1.gitlab")
+	if err := exportServer(test, "finished").Export(context.Background(), 1, backupFile); err != nil {
+		test.Fatal(err)
+	}
+
+	// 导出失败时返回异常
+	if err := exportServer(test, "failed").Export(context.Background(), 1, backupFile); err == nil || !strings.Contains(err.Error(), "export failed") {
+		test.Fatal(err)
+	}
+
+	// 导出未完成时 ctx 取消后结束轮询
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var start = time.Now()
+	err := exportServer(test, "started").Export(ctx, 1, backupFile)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 2*time.Second {
+		test.Fatal(err, time.Since(start))
+	}
+}
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/longyuan/gitlab.v3/console"
+	"github.com/longyuan/lib.v3/config"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/lib.v3/secret"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 	"os"
@@ -17,34 +20,34 @@ func Backup() []*cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			configPath, err := cmd.Flags().GetString("config")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			var host, token string
 			if configPath == "" {
 				token, err = cmd.Flags().GetString("token")
 				if err != nil {
-					color.Red(fmt.Sprint(err))
+					logger.Error(fmt.Sprint(err))
 					return
 				}
 				if token == "" {
-					color.Red("Not Set token value ?")
+					logger.Error("Not Set token value ?")
 					return
 				}
 				host, err = cmd.Flags().GetString("host")
 				if err != nil {
-					color.Red(fmt.Sprint(err))
+					logger.Error(fmt.Sprint(err))
 					return
 				}
 				if host == "" {
-					color.Red("Not Set host value ?")
+					logger.Error("Not Set host value ?")
 					return
 				}
 			} else {
 				// 读取Yaml 文件
 				fileBytes, err := os.ReadFile(configPath)
 				if err != nil {
-					color.Red(fmt.Sprint(err))
+					logger.Error(fmt.Sprint(err))
 					return
 				}
 				var gitlabConfig = struct {
@@ -53,73 +56,114 @@ func Backup() []*cobra.Command {
 				}{}
 				err = yaml.Unmarshal(fileBytes, &gitlabConfig)
 				if err != nil {
-					color.Red(fmt.Sprint(err))
+					logger.Error(fmt.Sprint(err))
 					return
 				}
 				host = gitlabConfig.Host
 				token = gitlabConfig.Token
 			}
+			// 支持密钥引用 (env:, file:, vault:)
+			token, err = secret.Resolve(token)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
 			output, err := cmd.Flags().GetString("output")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			_, err = console.Backup(host, token, output)
+			recipients, err := cmd.Flags().GetStringSlice("encrypt-recipient")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			_, err = console.Backup(host, token, output, format, recipients)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
 	}
 	backupCmd.Flags().StringP("host", "H", "", "Gitlab URL")
-	backupCmd.Flags().StringP("token", "t", "", "Gitlab Token")
+	backupCmd.Flags().StringP("token", "t", "", "Gitlab Token or Secret Reference (env:NAME, file:PATH, vault:NAME)")
 	backupCmd.Flags().StringP("config", "c", "", "Config")
 	backupCmd.Flags().StringP("output", "o", "", "Output File")
+	backupCmd.Flags().String("format", "zip", "Archive Format (zip|tar.gz|tar.zst)")
+	backupCmd.Flags().StringSlice("encrypt-recipient", nil, "Encrypt Recipient (age1... Public Key, Recipients File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
 
 	var cronBackupCmd = &cobra.Command{
 		Use:     "cron-backup",
 		Short:   "Backup Gitlab Repository",
-		Example: "backup -H gitlab.com -t WXZXXX1",
+		Example: "cron-backup -j gitlab-job.yaml",
 		Run: func(cmd *cobra.Command, args []string) {
-			configPath, err := cmd.Flags().GetString("config")
+			job, err := config.CronJob(cmd, config.SourceGitlab)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if configPath == "" {
-				color.Red("ConfigPath Required")
+			listen, err := cmd.Flags().GetString("listen")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			cron, err := cmd.Flags().GetString("cron")
+			runNow, err := cmd.Flags().GetBool("run-now")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if cron == "" {
-				color.Red("Cron Required")
+			err = console.CronBackup(job, listen, runNow)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			cloudStorageConfig, err := cmd.Flags().GetString("cloud-storage")
+		},
+	}
+	config.AddCronFlags(cronBackupCmd, config.SourceGitlab)
+
+	var verifyCmd = &cobra.Command{
+		Use:     "verify",
+		Short:   "Verify Backup Archive (manifest.json SHA-256)",
+		Example: "verify -f gitlab.tar.gz",
+		Run: func(cmd *cobra.Command, args []string) {
+			file, err := cmd.Flags().GetString("file")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if cloudStorageConfig == "" {
-				color.Red("CloudStorageConfig Required")
+			if file == "" {
+				logger.Error("File Required")
 				return
 			}
-			err = console.CronBackup(configPath, cron, cloudStorageConfig)
+			identities, err := cmd.Flags().GetStringSlice("identity")
 			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Verify(file, identities, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				os.Exit(1)
+			}
 		},
 	}
-	cronBackupCmd.Flags().StringP("config", "c", "", "Config")
-	cronBackupCmd.Flags().String("cron", "", "Cron")
-	cronBackupCmd.Flags().String("cloud-storage", "", "CloudStorage Config")
+	verifyCmd.Flags().StringP("file", "f", "", "Backup File or URL")
+	verifyCmd.Flags().StringSlice("identity", nil, "Decrypt Identity (AGE-SECRET-KEY-1... Private Key, Identity File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
+	verifyCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
 
 	return []*cobra.Command{
 		backupCmd,
 		cronBackupCmd,
+		verifyCmd,
 	}
 }
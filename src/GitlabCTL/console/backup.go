@@ -1,56 +1,85 @@
 package console
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/longyuan/gitlab.v3/client"
+	"github.com/longyuan/lib.v3/backup"
 	"github.com/longyuan/lib.v3/compress"
+	"github.com/longyuan/lib.v3/config"
 	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/encrypt"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/lib.v3/message"
+	"github.com/longyuan/lib.v3/scheduler"
 	"github.com/longyuan/storage.v3/storage"
-	"github.com/robfig/cron/v3"
-	"gopkg.in/yaml.v3"
+	"io"
 	"os"
 	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func Backup(host, token, outputFile string) (*string, error) {
-	gitlabClient, err := client.NewGitlabClient(host, token)
+// exportAttempts 单个项目最多导出次数
+const exportAttempts = 3
+
+// Backup 备份; format 归档格式 (zip, tar.gz, tar.zst), recipients 加密接收者 (为空不加密)
+func Backup(host, token, outputFile, format string, recipients []string) (*string, error) {
+	archiveFormat, err := compress.ParseFormat(format)
 	if err != nil {
 		return nil, err
 	}
-	color.Cyan("[Gitlab] 扫描项目列表 ...")
-	projects, err := gitlabClient.Projects()
+	backupDirectory, err := export(context.Background(), host, token)
 	if err != nil {
 		return nil, err
 	}
-	color.Blue("[Gitlab] 扫描完成，当前授权可访问项目共: %d", len(projects))
 
-	colorPrint := color.New()
-	colorPrint.Add(color.Bold)
-	colorPrint.Add(color.FgYellow)
-	_, err = colorPrint.Println("备份时间会很长，请耐心等待（不要关闭正在执行的程序）")
+	// 压缩文件
+	if outputFile == "" {
+		outputFile = "gitlab" + archiveFormat.Ext()
+	}
+	return encrypt.Archive(*backupDirectory, outputFile, archiveFormat, recipients, true)
+}
+
+// export 导出所有项目到临时目录; 单个项目最多导出 exportAttempts 次, ctx 取消时结束导出并删除临时目录
+func export(ctx context.Context, host, token string) (_ *string, err error) {
+	gitlabClient, err := client.NewGitlabClient(host, token)
 	if err != nil {
 		return nil, err
 	}
+	var manifest = backup.NewManifest(message.SourceGitlab, host)
+	logger.Info("[Gitlab] 扫描项目列表 ...")
+	projects, err := gitlabClient.Projects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("[Gitlab] 扫描完成，当前授权可访问项目共: %d", len(projects))
+
+	logger.Warn("备份时间会很长，请耐心等待（不要关闭正在执行的程序）")
 
 	// 临时目录
 	backupDirectory, err := ctl.CreateTempDirectory("gitlab")
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(*backupDirectory)
+		}
+	}()
 	for _, project := range projects {
-		for {
+		for attempt := 1; ; attempt++ {
+			if err = ctx.Err(); err != nil {
+				return nil, err
+			}
 			// 导出配置
 			jsonFile, err := json.Marshal(&project)
 			if err != nil {
 				return nil, err
 			}
-			color.Blue(fmt.Sprintf("[Gitlab] 导出项目: %s", project.Name))
+			logger.Info(fmt.Sprintf("[Gitlab] 导出项目: %s", project.Name))
 			var projectConfigPath = path.Join(*backupDirectory, fmt.Sprintf("project.%d.json", project.ID))
 			if _, err := os.Stat(projectConfigPath); err == nil || !os.IsNotExist(err) {
 				err := os.Remove(projectConfigPath)
@@ -69,90 +98,178 @@ func Backup(host, token, outputFile string) (*string, error) {
 			if _, err = os.Stat(projectOutputPath); err == nil || !os.IsNotExist(err) {
 				break
 			}
-			err = gitlabClient.Export(projectId, projectOutputPath)
+			err = gitlabClient.Export(ctx, projectId, projectOutputPath)
 			if err != nil {
-				color.Red("[Gitlab] 导出时发生异常 (等待3s): " + fmt.Sprint(err))
-				time.Sleep(3 * time.Second)
+				if ctx.Err() != nil || attempt >= exportAttempts {
+					return nil, fmt.Errorf("export project %s: %w", project.NameWithNamespace, err)
+				}
+				logger.Error("[Gitlab] 导出时发生异常 (等待3s): " + fmt.Sprint(err))
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(3 * time.Second):
+				}
 				continue
 			}
 			break
 		}
 	}
 
-	// 压缩文件 zip
-	if outputFile == "" {
-		outputFile = "gitlab.zip"
-	}
-	err = compress.Zip(*backupDirectory, outputFile, true)
+	// 备份清单
+	manifest.Count("project", len(projects))
+	err = manifest.Write(*backupDirectory)
 	if err != nil {
 		return nil, err
 	}
-	return &outputFile, nil
+	return backupDirectory, nil
+}
+
+// upload 压缩 (加密) 导出目录并流式上传到所有目标, 不生成本地压缩文件; 完成后删除导出目录
+func upload(ctx context.Context, targets []storage.Target, policy storage.Policy, backupDirectory, cloudPath string, format compress.Format, recipients []string, uploadOptions storage.UploadOptions) *storage.ReplicaResult {
+	defer func() {
+		_ = os.RemoveAll(backupDirectory)
+	}()
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(encrypt.Stream(backupDirectory, writer, format, recipients))
+	}()
+	uploadOptions.Progress = storage.PrintProgress(path.Base(cloudPath))
+	result := storage.ReplicateReader(ctx, targets, policy, reader, cloudPath, uploadOptions)
+	// 所有目标失败时结束压缩
+	_ = reader.CloseWithError(io.ErrClosedPipe)
+	return result
 }
 
-func CronBackup(configPath, backupCron, cloudStorageConfig string) error {
-	var values = strings.Split(cloudStorageConfig, ",")
-	if len(values) < 3 {
-		return fmt.Errorf("CloudStorageConfig error")
-	}
-	var url = values[0]
-	var secretId = values[1]
-	var secretKey = values[2]
-	cosClient, err := storage.NewTencentCOS(url, secretId, secretKey)
+// BackupJob 创建备份任务; 每次运行备份任务配置中 gitlab 类型的来源 (包含来源目录中的 *.yaml), 有来源失败时返回异常
+func BackupJob(job *config.Job) (scheduler.Func, error) {
+	archiveFormat, err := compress.ParseFormat(job.Archive.Format)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	c := cron.New()
-	_, err = c.AddFunc(backupCron, func() {
+	jobStorage, err := storage.NewJobStorage(job.Storage)
+	if err != nil {
+		return nil, err
+	}
+	router, err := job.Notice.Router()
+	if err != nil {
+		return nil, err
+	}
+	if job.SourceDir != "" {
+		logger.Info(fmt.Sprintf("ConfigPath: %s", job.SourceDir))
+	}
+	logger.Info(fmt.Sprintf("S3 Config: %s (policy %s)", strings.Join(storage.Names(jobStorage.Targets), ", "), jobStorage.Policy))
+	if job.Notice.Config != "" {
+		logger.Info(fmt.Sprintf("Notice Config: %s", job.Notice.Describe()))
+	}
+	if jobStorage.Retention.Enabled() {
+		logger.Info(fmt.Sprintf("Retention: %s", jobStorage.Retention))
+	}
+	if jobStorage.Upload.BandwidthLimit > 0 {
+		logger.Info(fmt.Sprintf("Bandwidth Limit: %d B/s", jobStorage.Upload.BandwidthLimit))
+	}
+	return func(ctx context.Context) error {
+		var jobLogger = logger.FromContext(ctx)
 		var dateFormat = time.Now().Format("2006_01_02")
 		var dateTimeFormat = time.Now().Format("2006_01_02_15_04_05")
-		tempDirectory, err := ctl.CreateTempDirectory("cron_gitlab", dateTimeFormat)
+		var events []message.Event
+		var failed []string
+		sources, err := job.ListSources(config.SourceGitlab)
 		if err != nil {
-			color.Red(fmt.Sprint(err))
-			return
+			jobLogger.Error(fmt.Sprint(err))
+			events = append(events, message.BackupEvent(message.SourceGitlab, job.SourceDir, "", err))
+			failed = append(failed, err.Error())
 		}
-		err = filepath.Walk(configPath, func(configItemPath string, fi os.FileInfo, errBack error) (err error) {
-			var fileName = fi.Name()
-			if !strings.HasSuffix(fileName, ".yaml") {
-				return nil
+		for _, source := range sources {
+			if err = ctx.Err(); err != nil {
+				failed = append(failed, err.Error())
+				break
 			}
-			// 读取Yaml 文件
-			fileBytes, err := os.ReadFile(configItemPath)
-			if err != nil {
-				return err
+			var sourceLogger = jobLogger.With(logger.Fields{"host": source.Host})
+			// 备份
+			var outFileName = source.Name + "_" + dateTimeFormat + archiveFormat.Ext()
+			if encrypt.Encrypted(job.Archive.Recipients) {
+				outFileName += encrypt.Ext
 			}
-			var gitlabConfig = struct {
-				Host  string `yaml:"host"`
-				Token string `yaml:"token"`
-			}{}
-			err = yaml.Unmarshal(fileBytes, &gitlabConfig)
+			backupDirectory, err := export(ctx, source.Host, source.Token)
 			if err != nil {
-				return err
+				sourceLogger.Error(fmt.Sprint(err))
+				events = append(events, message.BackupEvent(message.SourceGitlab, source.Name, "", err))
+				failed = append(failed, source.Name+": "+err.Error())
+				continue
 			}
-			// 备份
-			var outFileName = path.Base(fileName) + "_" + dateTimeFormat + ".zip"
-			var outputFile = path.Join(*tempDirectory, outFileName)
-			backupZipFile, err := Backup(gitlabConfig.Host, gitlabConfig.Token, outputFile)
-			if err != nil {
-				return err
+			var options = jobStorage.Upload
+			options.Put.Tags = map[string]string{"tool": message.SourceGitlab, "source": source.Host}
+			result := upload(ctx, jobStorage.Targets, jobStorage.Policy, *backupDirectory, "gitlab/"+dateFormat+"/"+outFileName, archiveFormat, job.Archive.Recipients, options)
+			if err = result.Err(); err != nil {
+				sourceLogger.Error(fmt.Sprint(err))
+				events = append(events, message.BackupEvent(message.SourceGitlab, source.Name, "", err))
+				failed = append(failed, source.Name+": "+err.Error())
+				continue
 			}
-			_, err = cosClient.Put(*backupZipFile, "gitlab/"+dateFormat+"/"+outFileName)
+			events = append(events, message.ReplicaBackupEvent(message.SourceGitlab, source.Name, result.String(), result.Degraded(), nil))
+			// 清理过期备份 (只清理本次上传成功的目标), 失败不影响本次备份结果
+			for _, target := range result.Uploaded(jobStorage.Targets) {
+				_, err = storage.Prune(target, "gitlab/", source.Name, jobStorage.Retention)
+				if err != nil {
+					sourceLogger.Error(fmt.Sprint(err))
+				}
+			}
+		}
+		// 消息通知
+		if router != nil && len(events) > 0 {
+			_, err = router.Dispatch("Gitlab 备份", events)
 			if err != nil {
-				return err
+				jobLogger.Error(fmt.Sprint(err))
 			}
-			return nil
-		})
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("backup failed: %s", strings.Join(failed, "; "))
+		}
+		return nil
+	}, nil
+}
+
+// CronBackup 定时备份 (见 BackupJob); 收到 SIGTERM 时等待运行中的备份结束; runNow 时启动后立即运行一次
+func CronBackup(job *config.Job, listen string, runNow bool) error {
+	run, err := BackupJob(job)
+	if err != nil {
+		return err
+	}
+	var name = job.Name
+	if name == "" {
+		name = config.SourceGitlab
+	}
+	var jobScheduler = scheduler.New(nil)
+	err = jobScheduler.Add(name, job.Schedule, run)
+	if err != nil {
+		return err
+	}
+	if listen != "" {
+		err = jobScheduler.Listen(listen)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Info(fmt.Sprintf("Cron (%s) Start Success ...", job.Schedule))
+	if runNow {
+		err = jobScheduler.Trigger(name)
 		if err != nil {
-			color.Red(fmt.Sprint(err))
+			return err
 		}
-	})
+	}
+	jobScheduler.Serve(scheduler.DefaultStopTimeout)
+	return nil
+}
+
+// Verify 校验备份归档; file 本地文件或下载地址, identities 解密密钥 (.age 加密归档), output 输出格式 (table|json|yaml|csv|markdown)
+func Verify(file string, identities []string, output string) error {
+	verification, err := backup.VerifyFile(file, identities)
+	if err != nil {
+		return err
+	}
+	err = verification.Print(output)
 	if err != nil {
 		return err
 	}
-	color.Blue(fmt.Sprintf("Cron (%s) Start Success ...", backupCron))
-	color.Blue(fmt.Sprintf("ConfigPath: %s", configPath))
-	color.Blue(fmt.Sprintf("S3 Config: %s", cloudStorageConfig))
-	c.Start()
-	select {}
+	return verification.Err()
 }
@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/longyuan/kubernetes.v3/console"
+	"github.com/longyuan/lib.v3/config"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 func Backup() []*cobra.Command {
@@ -15,74 +18,108 @@ func Backup() []*cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			configPath, err := cmd.Flags().GetString("config")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if configPath == "" {
-				color.Red("Not Set kubeconfig file ?")
+				logger.Error("Not Set kubeconfig file ?")
 				return
 			}
 			outputFile, err := cmd.Flags().GetString("output")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			color.Green(fmt.Sprintf("[Kubernetes] kubeconfig: %s , output: %s", configPath, outputFile))
-			_, err = console.Backup(configPath, outputFile, nil)
+			logger.Success(fmt.Sprintf("[Kubernetes] kubeconfig: %s , output: %s", configPath, outputFile))
+			format, err := cmd.Flags().GetString("format")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			recipients, err := cmd.Flags().GetStringSlice("encrypt-recipient")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			_, err = console.Backup(configPath, outputFile, format, recipients, nil)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 		},
 	}
 	backupCmd.Flags().StringP("config", "c", "", "Config Path")
 	backupCmd.Flags().StringP("output", "o", "", "Output Path")
+	backupCmd.Flags().String("format", "zip", "Archive Format (zip|tar.gz|tar.zst)")
+	backupCmd.Flags().StringSlice("encrypt-recipient", nil, "Encrypt Recipient (age1... Public Key, Recipients File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
 
 	var cronBackupCmd = &cobra.Command{
 		Use:     "cron-backup",
 		Short:   "Backup Kubernetes Config",
-		Example: "backup -c ./conf/kubeconfig-example.yaml -cron '0 0 * * ?' -cs URL,SecretId,SecretKey",
+		Example: "cron-backup -j kubernetes-job.yaml",
 		Run: func(cmd *cobra.Command, args []string) {
-			configPath, err := cmd.Flags().GetString("config")
+			job, err := config.CronJob(cmd, config.SourceKubernetes)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if configPath == "" {
-				color.Red("Not Set kubeconfig file ?")
+			listen, err := cmd.Flags().GetString("listen")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			cron, err := cmd.Flags().GetString("cron")
+			runNow, err := cmd.Flags().GetBool("run-now")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if cron == "" {
-				color.Red("Not Set Cron ?")
+			err = console.CronBackup(job, listen, runNow)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			cloudStorage, err := cmd.Flags().GetString("cloud-storage")
+		},
+	}
+	config.AddCronFlags(cronBackupCmd, config.SourceKubernetes)
+
+	var verifyCmd = &cobra.Command{
+		Use:     "verify",
+		Short:   "Verify Backup Archive (manifest.json SHA-256)",
+		Example: "verify -f kubernetes.zip.age --identity key.txt",
+		Run: func(cmd *cobra.Command, args []string) {
+			file, err := cmd.Flags().GetString("file")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if cloudStorage == "" {
-				color.Red("Not Set CloudStorage Config ?")
+			if file == "" {
+				logger.Error("File Required")
 				return
 			}
-			err = console.CronBackup(configPath, cron, cloudStorage)
+			identities, err := cmd.Flags().GetStringSlice("identity")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Verify(file, identities, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				os.Exit(1)
+			}
 		},
 	}
-	cronBackupCmd.Flags().StringP("config", "c", "", "Config Path")
-	cronBackupCmd.Flags().String("cron", "", "Cron")
-	cronBackupCmd.Flags().String("cloud-storage", "", "CloudStorage Config")
+	verifyCmd.Flags().StringP("file", "f", "", "Backup File or URL")
+	verifyCmd.Flags().StringSlice("identity", nil, "Decrypt Identity (AGE-SECRET-KEY-1... Private Key, Identity File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
+	verifyCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
 
 	return []*cobra.Command{
 		backupCmd,
 		cronBackupCmd,
+		verifyCmd,
 	}
 }
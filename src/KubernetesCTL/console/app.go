@@ -4,7 +4,7 @@ import v1 "k8s.io/api/core/v1"
 
 func Copy(sourceConfigPath, sourceNamespace, targetConfigPath, targetNamespace string) {
 	// 备份
-	backupPath, err := Backup(sourceConfigPath, "./copy.zip", func(namespace v1.Namespace) bool {
+	backupPath, err := Backup(sourceConfigPath, "./copy.zip", "zip", nil, func(namespace v1.Namespace) bool {
 		return namespace.ObjectMeta.Name == sourceNamespace
 	})
 	if err != nil {
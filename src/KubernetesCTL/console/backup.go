@@ -1,19 +1,23 @@
 package console
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/longyuan/kubernetes.v3/client"
+	"github.com/longyuan/lib.v3/backup"
 	"github.com/longyuan/lib.v3/compress"
+	"github.com/longyuan/lib.v3/config"
 	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/encrypt"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/lib.v3/message"
+	"github.com/longyuan/lib.v3/scheduler"
 	"github.com/longyuan/storage.v3/storage"
-	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v3"
 	v1 "k8s.io/api/core/v1"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
 	"time"
 )
@@ -21,10 +25,15 @@ import (
 type BackupClient struct {
 	rootPath string
 	client   *client.KClient
+	manifest *backup.Manifest
 }
 
-// Backup 备份
-func Backup(configPath, outputFile string, filter func(namespace v1.Namespace) bool) (*string, error) {
+// Backup 备份; format 归档格式 (zip, tar.gz, tar.zst), recipients 加密接收者 (为空不加密)
+func Backup(configPath, outputFile, format string, recipients []string, filter func(namespace v1.Namespace) bool) (*string, error) {
+	archiveFormat, err := compress.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
 	// 创建客户端
 	kClient, err := client.NewKClient(configPath)
 	if err != nil {
@@ -45,6 +54,7 @@ func Backup(configPath, outputFile string, filter func(namespace v1.Namespace) b
 	var backupClient = &BackupClient{
 		client:   kClient,
 		rootPath: *backupDirectory,
+		manifest: backup.NewManifest(message.SourceKubernetes, kClient.Name),
 	}
 	for _, namespace := range namespaces {
 		if filter != nil && !filter(namespace) {
@@ -101,69 +111,156 @@ func Backup(configPath, outputFile string, filter func(namespace v1.Namespace) b
 		}
 	}
 
-	// 压缩文件 zip
+	// 压缩文件
 	if outputFile == "" {
-		outputFile = kClient.Name + ".zip"
+		outputFile = kClient.Name + archiveFormat.Ext()
 	}
-	err = compress.Zip(*backupDirectory, outputFile, true)
+	err = backupClient.manifest.Write(*backupDirectory)
 	if err != nil {
 		return nil, err
 	}
-	return &outputFile, nil
+	return encrypt.Archive(*backupDirectory, outputFile, archiveFormat, recipients, true)
 }
 
-// CronBackup 备份
-func CronBackup(configPath, backupCron, cloudStorageConfig string) error {
-	var values = strings.Split(cloudStorageConfig, ",")
-	if len(values) < 3 {
-		return fmt.Errorf("CloudStorageConfig error")
-	}
-	var url = values[0]
-	var secretId = values[1]
-	var secretKey = values[2]
-	cosClient, err := storage.NewTencentCOS(url, secretId, secretKey)
+// BackupJob 创建备份任务; 每次运行备份任务配置中 kubernetes 类型的来源 (包含来源目录中的 kubeconfig *.yaml), 有来源失败时返回异常
+func BackupJob(job *config.Job) (scheduler.Func, error) {
+	archiveFormat, err := compress.ParseFormat(job.Archive.Format)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	c := cron.New()
-	_, err = c.AddFunc(backupCron, func() {
+	jobStorage, err := storage.NewJobStorage(job.Storage)
+	if err != nil {
+		return nil, err
+	}
+	router, err := job.Notice.Router()
+	if err != nil {
+		return nil, err
+	}
+	if job.SourceDir != "" {
+		logger.Info(fmt.Sprintf("ConfigPath: %s", job.SourceDir))
+	}
+	logger.Info(fmt.Sprintf("S3 Config: %s (policy %s)", strings.Join(storage.Names(jobStorage.Targets), ", "), jobStorage.Policy))
+	if job.Notice.Config != "" {
+		logger.Info(fmt.Sprintf("Notice Config: %s", job.Notice.Describe()))
+	}
+	if jobStorage.Retention.Enabled() {
+		logger.Info(fmt.Sprintf("Retention: %s", jobStorage.Retention))
+	}
+	if jobStorage.Upload.BandwidthLimit > 0 {
+		logger.Info(fmt.Sprintf("Bandwidth Limit: %d B/s", jobStorage.Upload.BandwidthLimit))
+	}
+	return func(ctx context.Context) error {
+		var jobLogger = logger.FromContext(ctx)
 		var dateFormat = time.Now().Format("2006_01_02")
 		var dateTimeFormat = time.Now().Format("2006_01_02_15_04_05")
 		tempDirectory, err := ctl.CreateTempDirectory("cron_kubernetes", dateTimeFormat)
 		if err != nil {
-			color.Red(fmt.Sprint(err))
-			return
+			return err
+		}
+		var events []message.Event
+		var failed []string
+		sources, err := job.ListSources(config.SourceKubernetes)
+		if err != nil {
+			jobLogger.Error(fmt.Sprint(err))
+			events = append(events, message.BackupEvent(message.SourceKubernetes, job.SourceDir, "", err))
+			failed = append(failed, err.Error())
 		}
-		err = filepath.Walk(configPath, func(configItemPath string, fi os.FileInfo, errBack error) (err error) {
-			var fileName = fi.Name()
-			if !strings.HasSuffix(fileName, ".yaml") {
-				return nil
+		for _, source := range sources {
+			if err = ctx.Err(); err != nil {
+				failed = append(failed, err.Error())
+				break
+			}
+			var sourceLogger = jobLogger.With(logger.Fields{"source": source.Name})
+			// 来源目录中的配置文件本身就是 kubeconfig
+			var kubeconfig = source.Kubeconfig
+			if kubeconfig == "" {
+				kubeconfig = source.File
 			}
-			var outFileName = path.Base(fileName) + "_" + dateTimeFormat + ".zip"
+			var outFileName = source.Name + "_" + dateTimeFormat + archiveFormat.Ext()
 			var outputFile = path.Join(*tempDirectory, outFileName)
-			backupZipFile, err := Backup(configItemPath, outputFile, nil)
+			backupZipFile, err := Backup(kubeconfig, outputFile, job.Archive.Format, job.Archive.Recipients, nil)
 			if err != nil {
-				return err
+				sourceLogger.Error(fmt.Sprint(err))
+				events = append(events, message.BackupEvent(message.SourceKubernetes, source.Name, "", err))
+				failed = append(failed, source.Name+": "+err.Error())
+				continue
+			}
+			var options = jobStorage.Upload
+			options.Progress = storage.PrintProgress(path.Base(*backupZipFile))
+			options.Put.Tags = map[string]string{"tool": message.SourceKubernetes, "cluster": strings.TrimSuffix(source.Name, path.Ext(source.Name))}
+			result := storage.Replicate(ctx, jobStorage.Targets, jobStorage.Policy, *backupZipFile, "kubernetes/"+dateFormat+"/"+path.Base(*backupZipFile), options)
+			if err = result.Err(); err != nil {
+				sourceLogger.Error(fmt.Sprint(err))
+				events = append(events, message.BackupEvent(message.SourceKubernetes, source.Name, "", err))
+				failed = append(failed, source.Name+": "+err.Error())
+				continue
 			}
-			_, err = cosClient.Put(*backupZipFile, "kubernetes/"+dateFormat+"/"+outFileName)
+			events = append(events, message.ReplicaBackupEvent(message.SourceKubernetes, source.Name, result.String(), result.Degraded(), nil))
+			// 清理过期备份 (只清理本次上传成功的目标), 失败不影响本次备份结果
+			for _, target := range result.Uploaded(jobStorage.Targets) {
+				_, err = storage.Prune(target, "kubernetes/", source.Name, jobStorage.Retention)
+				if err != nil {
+					sourceLogger.Error(fmt.Sprint(err))
+				}
+			}
+		}
+		// 消息通知
+		if router != nil && len(events) > 0 {
+			_, err = router.Dispatch("Kubernetes 备份", events)
 			if err != nil {
-				return err
+				jobLogger.Error(fmt.Sprint(err))
 			}
-			return nil
-		})
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("backup failed: %s", strings.Join(failed, "; "))
+		}
+		return nil
+	}, nil
+}
+
+// CronBackup 定时备份 (见 BackupJob); 收到 SIGTERM 时等待运行中的备份结束; runNow 时启动后立即运行一次
+func CronBackup(job *config.Job, listen string, runNow bool) error {
+	run, err := BackupJob(job)
+	if err != nil {
+		return err
+	}
+	var name = job.Name
+	if name == "" {
+		name = config.SourceKubernetes
+	}
+	var jobScheduler = scheduler.New(nil)
+	err = jobScheduler.Add(name, job.Schedule, run)
+	if err != nil {
+		return err
+	}
+	if listen != "" {
+		err = jobScheduler.Listen(listen)
 		if err != nil {
-			color.Red(fmt.Sprint(err))
+			return err
 		}
-	})
+	}
+	logger.Success(fmt.Sprintf("Cron (%s) Start Success ...", job.Schedule))
+	if runNow {
+		err = jobScheduler.Trigger(name)
+		if err != nil {
+			return err
+		}
+	}
+	jobScheduler.Serve(scheduler.DefaultStopTimeout)
+	return nil
+}
+
+// Verify 校验备份归档; file 本地文件或下载地址, identities 解密密钥 (.age 加密归档), output 输出格式 (table|json|yaml|csv|markdown)
+func Verify(file string, identities []string, output string) error {
+	verification, err := backup.VerifyFile(file, identities)
 	if err != nil {
 		return err
 	}
-	color.Green(fmt.Sprintf("Cron (%s) Start Success ...", backupCron))
-	color.Blue(fmt.Sprintf("ConfigPath: %s", configPath))
-	color.Blue(fmt.Sprintf("S3 Config: %s", cloudStorageConfig))
-	c.Start()
-	select {}
+	err = verification.Print(output)
+	if err != nil {
+		return err
+	}
+	return verification.Err()
 }
 
 func (backup *BackupClient) createDirectory(values ...string) (*string, error) {
@@ -191,6 +288,9 @@ func (backup *BackupClient) output(value any, output ...string) error {
 	if err != nil {
 		return err
 	}
+	if kind, ok := m["kind"].(string); ok {
+		backup.manifest.Count(kind, 1)
+	}
 	err = os.WriteFile(path.Join(output...), []byte(strings.ReplaceAll(string(data), "    ", "  ")), 644)
 	if err != nil {
 		return err
@@ -199,7 +299,7 @@ func (backup *BackupClient) output(value any, output ...string) error {
 }
 
 func (backup *BackupClient) backupNamespace(namespaceName string) error {
-	color.Green(fmt.Sprintf("[Kubernetes] Backup Namespace: %s", namespaceName))
+	logger.Success(fmt.Sprintf("[Kubernetes] Backup Namespace: %s", namespaceName))
 	localPath, err := backup.createDirectory("namespaces", namespaceName)
 	if err != err {
 		return err
@@ -229,7 +329,7 @@ func (backup *BackupClient) backupDeployment(namespaceName string) error {
 	for _, item := range deployments {
 		item.Kind = "Deployment"
 		item.APIVersion = "apps/v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup Deployment: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup Deployment: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -250,7 +350,7 @@ func (backup *BackupClient) backupStatefulSet(namespaceName string) error {
 	for _, item := range statefulSets {
 		item.Kind = "StatefulSet"
 		item.APIVersion = "apps/v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup StatefulSet: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup StatefulSet: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -271,7 +371,7 @@ func (backup *BackupClient) backupDaemonSet(namespaceName string) error {
 	for _, item := range daemonSets {
 		item.Kind = "DaemonSet"
 		item.APIVersion = "apps/v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup DaemonSet: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup DaemonSet: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -292,7 +392,7 @@ func (backup *BackupClient) backupJob(namespaceName string) error {
 	for _, item := range jobs {
 		item.Kind = "Job"
 		item.APIVersion = "batch/v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup Job: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup Job: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -313,7 +413,7 @@ func (backup *BackupClient) backupCronJob(namespaceName string) error {
 	for _, item := range cronJobs {
 		item.Kind = "CronJob"
 		item.APIVersion = "batch/v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup CronJob: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup CronJob: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -334,7 +434,7 @@ func (backup *BackupClient) backupConfigmap(namespaceName string) error {
 	for _, item := range configmaps {
 		item.Kind = "ConfigMap"
 		item.APIVersion = "v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup ConfigMap: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup ConfigMap: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -355,7 +455,7 @@ func (backup *BackupClient) backupSecret(namespaceName string) error {
 	for _, item := range secrets {
 		item.Kind = "Secret"
 		item.APIVersion = "v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup Secret: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup Secret: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -376,7 +476,7 @@ func (backup *BackupClient) backupService(namespaceName string) error {
 	for _, item := range services {
 		item.Kind = "Service"
 		item.APIVersion = "v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup Service: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup Service: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -397,7 +497,7 @@ func (backup *BackupClient) backupIngress(namespaceName string) error {
 	for _, item := range secrets {
 		item.Kind = "Ingress"
 		item.APIVersion = "networking.k8s.io/v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup Ingress: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup Ingress: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -418,7 +518,7 @@ func (backup *BackupClient) backupPersistentVolumeClaim(namespaceName string) er
 	for _, item := range persistentVolumeClaims {
 		item.Kind = "PersistentVolumeClaim"
 		item.APIVersion = "v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup PersistentVolumeClaim: %s / %s", namespaceName, item.ObjectMeta.Name))
+		logger.With(logger.Fields{"namespace": namespaceName}).Success(fmt.Sprintf("[Kubernetes] Backup PersistentVolumeClaim: %s / %s", namespaceName, item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
@@ -439,7 +539,7 @@ func (backup *BackupClient) backupPersistentVolume() error {
 	for _, item := range persistentVolumes {
 		item.Kind = "PersistentVolume"
 		item.APIVersion = "v1"
-		color.Green(fmt.Sprintf("[Kubernetes] Backup PersistentVolume: %s", item.ObjectMeta.Name))
+		logger.Success(fmt.Sprintf("[Kubernetes] Backup PersistentVolume: %s", item.ObjectMeta.Name))
 		err = backup.output(item, *localPath, item.ObjectMeta.Name+".yaml")
 		if err != nil {
 			return err
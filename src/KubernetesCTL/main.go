@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/longyuan/kubernetes.v3/cmd"
+	"github.com/longyuan/lib.v3/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -10,6 +11,7 @@ func main() {
 	for _, it := range cmd.Backup() {
 		rootCmd.AddCommand(it)
 	}
+	logger.AddFlags(rootCmd, "kubernetes")
 	err := rootCmd.Execute()
 	if err != nil {
 		return
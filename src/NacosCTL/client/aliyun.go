@@ -6,10 +6,10 @@ import (
 	mse "github.com/alibabacloud-go/mse-20190531/v3/client"
 	util "github.com/alibabacloud-go/tea-utils/service"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/longyuan/lib.v3/logger"
 	"github.com/samber/lo"
 	"io"
 	"io/fs"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
@@ -160,7 +160,7 @@ func (aliyun *Aliyun) PullNacos(namespaceId string, rootPath string) error {
 	}
 	for i := range configList {
 		var item = configList[i]
-		log.Println(">>", namespaceId, "/", *item.DataId)
+		logger.With(logger.Fields{"namespace": namespaceId}).Info(">> %s / %s", namespaceId, *item.DataId)
 		config, err := aliyun.GetNacosConfig(namespaceId, *item.Group, *item.DataId)
 		if err != nil {
 			return err
@@ -183,9 +183,10 @@ func (aliyun *Aliyun) PullNacos(namespaceId string, rootPath string) error {
 	return nil
 }
 
-// Sync 同步配置到线上.
+// Sync 同步配置到线上; 单个配置失败时继续同步其它配置, 最后返回所有失败的配置.
 func (aliyun *Aliyun) Sync(namespaceId string, rootPath string) (*bool, error) {
-	log.Println(fmt.Sprintf("Sync Nacos Config By NamespaceId: %s", namespaceId))
+	var syncLogger = logger.With(logger.Fields{"namespace": namespaceId})
+	syncLogger.Info(fmt.Sprintf("Sync Nacos Config By NamespaceId: %s", namespaceId))
 	// 读取本地磁盘
 	var files []string
 	var pathSeparator = string(os.PathSeparator)
@@ -265,27 +266,34 @@ func (aliyun *Aliyun) Sync(namespaceId string, rootPath string) (*bool, error) {
 		}
 	}
 
-	log.Println(fmt.Sprintf("Create: %d条", len(addTask)))
+	var failed []string
+	syncLogger.Info(fmt.Sprintf("Create: %d条", len(addTask)))
 	lo.ForEach(addTask, func(it string, i int) {
-		_, err := aliyun.CreateNacosConfig(namespaceId, "DEFAULT_GROUP",
-			strings.TrimSuffix(it, path.Ext(it)), &addTaskConfig[i], strings.TrimPrefix(path.Ext(it), "."))
+		var dataId = strings.TrimSuffix(it, path.Ext(it))
+		_, err := aliyun.CreateNacosConfig(namespaceId, "DEFAULT_GROUP", dataId, &addTaskConfig[i], strings.TrimPrefix(path.Ext(it), "."))
 		if err != nil {
-			log.Fatalf("Error:%s", err)
+			syncLogger.Error("Create %s: %s", dataId, err)
+			failed = append(failed, "create "+dataId+": "+err.Error())
 		}
 	})
-	log.Println(fmt.Sprintf("Update: %d条", len(updateTask)))
+	syncLogger.Info(fmt.Sprintf("Update: %d条", len(updateTask)))
 	lo.ForEach(updateTask, func(it string, i int) {
 		_, err := aliyun.UpdateNacosConfig(namespaceId, "DEFAULT_GROUP", updateConfigId[i], &updateConfig[i], path.Ext(it))
 		if err != nil {
-			log.Fatalf("Error:%s", err)
+			syncLogger.Error("Update %s: %s", updateConfigId[i], err)
+			failed = append(failed, "update "+updateConfigId[i]+": "+err.Error())
 		}
 	})
-	log.Println(fmt.Sprintf("Delete: %d条", len(deleteTask)))
+	syncLogger.Info(fmt.Sprintf("Delete: %d条", len(deleteTask)))
 	lo.ForEach(deleteTask, func(it mse.ListNacosConfigsResponseBodyConfigurations, _ int) {
 		_, err := aliyun.DeleteNacosConfig(namespaceId, *it.Group, *it.DataId)
 		if err != nil {
-			log.Fatalf("Error:%s", err)
+			syncLogger.Error("Delete %s: %s", *it.DataId, err)
+			failed = append(failed, "delete "+*it.DataId+": "+err.Error())
 		}
 	})
+	if len(failed) > 0 {
+		return tea.Bool(false), fmt.Errorf("sync %s: %d failed: %s", namespaceId, len(failed), strings.Join(failed, "; "))
+	}
 	return tea.Bool(true), nil
 }
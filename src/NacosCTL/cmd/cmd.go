@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/fatih/color"
+	"github.com/longyuan/lib.v3/config"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/lib.v3/secret"
 	"github.com/longyuan/nacos.v3/console"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 func Backup() []*cobra.Command {
@@ -15,46 +19,69 @@ func Backup() []*cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			host, err := cmd.Flags().GetString("host")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if host == "" {
-				color.Red("Host Required")
+				logger.Error("Host Required")
 				return
 			}
 			username, err := cmd.Flags().GetString("username")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if username == "" {
-				color.Red("Username Required")
+				logger.Error("Username Required")
 				return
 			}
 			password, err := cmd.Flags().GetString("password")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if password == "" {
-				color.Red("Password Required")
+				logger.Error("Password Required")
+				return
+			}
+			password, err = secret.Resolve(password)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			output, err := cmd.Flags().GetString("output")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			recipients, err := cmd.Flags().GetStringSlice("encrypt-recipient")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			summaryOutput, err := cmd.Flags().GetString("summary-output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			_, err = console.Backup(host, username, password, output)
+			_, err = console.Backup(host, username, password, output, format, recipients, summaryOutput)
 			if err != nil {
 				return
 			}
 		},
 	}
 	backupCmd.Flags().StringP("username", "u", "", "Nacos Username")
-	backupCmd.Flags().StringP("password", "p", "", "Nacos Password")
+	backupCmd.Flags().StringP("password", "p", "", "Nacos Password or Secret Reference (env:NAME, file:PATH, vault:NAME)")
 	backupCmd.Flags().StringP("host", "H", "", "Nacos Host")
 	backupCmd.Flags().StringP("output", "o", "", "Nacos Output")
+	backupCmd.Flags().String("format", "zip", "Archive Format (zip|tar.gz|tar.zst)")
+	backupCmd.Flags().StringSlice("encrypt-recipient", nil, "Encrypt Recipient (age1... Public Key, Recipients File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
+	backupCmd.Flags().String("summary-output", ctl.OutputTable, "Namespace Summary "+ctl.OutputUsage)
 
 	var backupAliyunCmd = &cobra.Command{
 		Use:     "ali-backup",
@@ -63,102 +90,189 @@ func Backup() []*cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			accessKeyId, err := cmd.Flags().GetString("accessKeyId")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if accessKeyId == "" {
-				color.Red("AccessKeyId Required")
+				logger.Error("AccessKeyId Required")
 				return
 			}
 			accessKeySecret, err := cmd.Flags().GetString("accessKeySecret")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if accessKeySecret == "" {
-				color.Red("AccessKeySecret Required")
+				logger.Error("AccessKeySecret Required")
+				return
+			}
+			accessKeySecret, err = secret.Resolve(accessKeySecret)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			instanceId, err := cmd.Flags().GetString("instanceId")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if instanceId == "" {
-				color.Red("InstanceId Required")
+				logger.Error("InstanceId Required")
 				return
 			}
 			namespace, err := cmd.Flags().GetString("namespace")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
 			if namespace == "" {
-				color.Red("Namespace Required")
+				logger.Error("Namespace Required")
 				return
 			}
 			output, err := cmd.Flags().GetString("output")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			_, err = console.AliBackup(accessKeyId, accessKeySecret, instanceId, namespace, output)
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			recipients, err := cmd.Flags().GetStringSlice("encrypt-recipient")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			_, err = console.AliBackup(accessKeyId, accessKeySecret, instanceId, namespace, output, format, recipients)
 			if err != nil {
 				return
 			}
 		},
 	}
 	backupAliyunCmd.Flags().StringP("accessKeyId", "k", "", "Aliyun AccessKeyId")
-	backupAliyunCmd.Flags().StringP("accessKeySecret", "s", "", "Aliyun AccessKeySecret")
+	backupAliyunCmd.Flags().StringP("accessKeySecret", "s", "", "Aliyun AccessKeySecret or Secret Reference (env:NAME, file:PATH, vault:NAME)")
 	backupAliyunCmd.Flags().StringP("instanceId", "i", "", "Aliyun InstanceId")
 	backupAliyunCmd.Flags().StringP("namespace", "n", "", "Aliyun InstanceId Namespace")
 	backupAliyunCmd.Flags().StringP("output", "o", "", "Nacos Output")
+	backupAliyunCmd.Flags().String("format", "zip", "Archive Format (zip|tar.gz|tar.zst)")
+	backupAliyunCmd.Flags().StringSlice("encrypt-recipient", nil, "Encrypt Recipient (age1... Public Key, Recipients File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
 
 	var cronBackupCmd = &cobra.Command{
 		Use:     "cron-backup",
 		Short:   "Backup Nacos Config",
-		Example: "backup -u nacos -p 1234 -h 127.0.0.1",
+		Example: "cron-backup -j nacos-job.yaml",
+		Run: func(cmd *cobra.Command, args []string) {
+			job, err := config.CronJob(cmd, config.SourceNacos)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			listen, err := cmd.Flags().GetString("listen")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			runNow, err := cmd.Flags().GetBool("run-now")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.CronBackup(job, listen, runNow)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	config.AddCronFlags(cronBackupCmd, config.SourceNacos)
+
+	var namespacesCmd = &cobra.Command{
+		Use:     "namespaces",
+		Short:   "List Nacos Namespaces",
+		Example: "namespaces -u nacos -p 1234 -H 127.0.0.1 --output json",
 		Run: func(cmd *cobra.Command, args []string) {
-			configPath, err := cmd.Flags().GetString("config")
+			host, err := cmd.Flags().GetString("host")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if configPath == "" {
-				color.Red("ConfigPath Required")
+			if host == "" {
+				logger.Error("Host Required")
 				return
 			}
-			cron, err := cmd.Flags().GetString("cron")
+			username, err := cmd.Flags().GetString("username")
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if cron == "" {
-				color.Red("Cron Required")
+			password, err := cmd.Flags().GetString("password")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			cloudStorageConfig, err := cmd.Flags().GetString("cloud-storage")
+			password, err = secret.Resolve(password)
 			if err != nil {
-				color.Red(fmt.Sprint(err))
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			if cloudStorageConfig == "" {
-				color.Red("CloudStorageConfig Required")
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
-			err = console.CronBackup(configPath, cron, cloudStorageConfig)
+			err = console.Namespaces(host, username, password, output)
 			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				os.Exit(1)
+			}
+		},
+	}
+	namespacesCmd.Flags().StringP("username", "u", "", "Nacos Username")
+	namespacesCmd.Flags().StringP("password", "p", "", "Nacos Password or Secret Reference (env:NAME, file:PATH, vault:NAME)")
+	namespacesCmd.Flags().StringP("host", "H", "", "Nacos Host")
+	namespacesCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
+
+	var verifyCmd = &cobra.Command{
+		Use:     "verify",
+		Short:   "Verify Backup Archive (manifest.json SHA-256)",
+		Example: "verify -f nacos.zip",
+		Run: func(cmd *cobra.Command, args []string) {
+			file, err := cmd.Flags().GetString("file")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
 				return
 			}
+			if file == "" {
+				logger.Error("File Required")
+				return
+			}
+			identities, err := cmd.Flags().GetStringSlice("identity")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Verify(file, identities, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				os.Exit(1)
+			}
 		},
 	}
-	cronBackupCmd.Flags().StringP("config", "c", "", "Config")
-	cronBackupCmd.Flags().String("cron", "", "Cron")
-	cronBackupCmd.Flags().String("cloud-storage", "", "CloudStorage Config")
+	verifyCmd.Flags().StringP("file", "f", "", "Backup File or URL")
+	verifyCmd.Flags().StringSlice("identity", nil, "Decrypt Identity (AGE-SECRET-KEY-1... Private Key, Identity File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
+	verifyCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
 
 	return []*cobra.Command{
 		backupCmd,
 		backupAliyunCmd,
 		cronBackupCmd,
+		namespacesCmd,
+		verifyCmd,
 	}
 }
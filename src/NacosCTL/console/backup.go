@@ -1,25 +1,68 @@
 package console
 
 import (
+	"context"
 	"fmt"
 	mse "github.com/alibabacloud-go/mse-20190531/v3/client"
-	"github.com/fatih/color"
+	"github.com/longyuan/lib.v3/backup"
 	"github.com/longyuan/lib.v3/compress"
+	"github.com/longyuan/lib.v3/config"
 	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/encrypt"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/lib.v3/message"
+	"github.com/longyuan/lib.v3/scheduler"
 	"github.com/longyuan/nacos.v3/client"
 	"github.com/longyuan/storage.v3/storage"
-	"github.com/robfig/cron/v3"
-	"gopkg.in/yaml.v3"
-	"log"
 	"os"
 	"path"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 )
 
-func Backup(host, username, password, outputFile string) (*string, error) {
+// namespaceRow 命名空间
+type namespaceRow struct {
+	Namespace   string `json:"namespace" title:"Nacos 命名空间ID"`
+	Name        string `json:"name" title:"Nacos 命名空间名称"`
+	ConfigCount int    `json:"configCount" title:"配置数量"`
+}
+
+func namespaceRows(namespaces []client.NacosNamespace) []namespaceRow {
+	var rows []namespaceRow
+	for _, item := range namespaces {
+		rows = append(rows, namespaceRow{Namespace: item.Namespace, Name: item.NamespaceShowName, ConfigCount: item.ConfigCount})
+	}
+	return rows
+}
+
+// Namespaces 输出命名空间和配置数量; output 输出格式 (table|json|yaml|csv|markdown)
+func Namespaces(host, username, password, output string) error {
+	output, err := ctl.ParseOutput(output)
+	if err != nil {
+		return err
+	}
+	nacosClient, err := client.NewNacosClient(host, username, password)
+	if err != nil {
+		return err
+	}
+	namespaces, err := nacosClient.Namespaces()
+	if err != nil {
+		return err
+	}
+	return ctl.Print(output, namespaceRows(namespaces.Data))
+}
+
+// Backup 备份; format 归档格式 (zip, tar.gz, tar.zst), recipients 加密接收者 (为空不加密),
+// summaryOutput 命名空间列表的输出格式 (table|json|yaml|csv|markdown, 默认 table)
+func Backup(host, username, password, outputFile, format string, recipients []string, summaryOutput string) (*string, error) {
+	archiveFormat, err := compress.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	summaryOutput, err = ctl.ParseOutput(summaryOutput)
+	if err != nil {
+		return nil, err
+	}
 	// 创建客户端
 	nacosClient, err := client.NewNacosClient(host, username, password)
 	if err != nil {
@@ -33,22 +76,21 @@ func Backup(host, username, password, outputFile string) (*string, error) {
 		return nil, err
 	}
 
+	var manifest = backup.NewManifest(message.SourceNacos, host)
+
 	// 打印命名空间
 	namespaces, err := nacosClient.Namespaces()
 	if err != nil {
 		return nil, err
 	}
-	var namespaceTable [][]string
-	for _, item := range namespaces.Data {
-		namespaceTable = append(namespaceTable, []string{
-			item.Namespace, item.NamespaceShowName, strconv.Itoa(item.ConfigCount),
-		})
+	err = ctl.Print(summaryOutput, namespaceRows(namespaces.Data))
+	if err != nil {
+		return nil, err
 	}
-	ctl.PrintTable([]string{"Nacos 命名空间ID", "Nacos 命名空间名称", "配置数量"}, namespaceTable)
 
 	// 执行备份
 	for _, it := range namespaces.Data {
-		color.Blue(fmt.Sprintf("[Nacos] 备份命名空间 %s  /  %s", it.Namespace, it.NamespaceShowName))
+		logger.With(logger.Fields{"host": host, "namespace": it.Namespace}).Info(fmt.Sprintf("[Nacos] 备份命名空间 %s  /  %s", it.Namespace, it.NamespaceShowName))
 		var namespaceId = it.Namespace
 		if namespaceId == "" {
 			continue
@@ -68,6 +110,8 @@ func Backup(host, username, password, outputFile string) (*string, error) {
 		}
 
 		// Items
+		manifest.Count("namespace", 1)
+		manifest.Count("config", len(items))
 		for _, item := range items {
 			var outputPath = path.Join(namespacePath, item.DataId+"."+item.Type)
 			err = os.WriteFile(outputPath, []byte(item.Content), 644)
@@ -78,25 +122,24 @@ func Backup(host, username, password, outputFile string) (*string, error) {
 	}
 
 	// 备份完成
-	colorPrint := color.New()
-	colorPrint.Add(color.Bold)
-	colorPrint.Add(color.FgGreen)
-	_, err = colorPrint.Println("[Nacos] " + time.Now().Format("2006-01-02 15:04:05") + " 备份完成")
-	if err != nil {
-		return nil, err
-	}
-	// 压缩文件 zip
+	logger.Success("[Nacos] " + time.Now().Format("2006-01-02 15:04:05") + " 备份完成")
+	// 压缩文件
 	if outputFile == "" {
-		outputFile = "nacos.zip"
+		outputFile = "nacos" + archiveFormat.Ext()
 	}
-	err = compress.Zip(*backupDirectory, outputFile, true)
+	err = manifest.Write(*backupDirectory)
 	if err != nil {
 		return nil, err
 	}
-	return &outputFile, nil
+	return encrypt.Archive(*backupDirectory, outputFile, archiveFormat, recipients, true)
 }
 
-func AliBackup(accessKeyId, accessKeySecret, instanceId, namespace, outputFile string) (*string, error) {
+// AliBackup 阿里云 MSE Nacos 备份; format 归档格式 (zip, tar.gz, tar.zst), recipients 加密接收者 (为空不加密)
+func AliBackup(accessKeyId, accessKeySecret, instanceId, namespace, outputFile, format string, recipients []string) (*string, error) {
+	archiveFormat, err := compress.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
 	// 创建客户端
 	nacosClient, err := client.NewAliyunNacosClient(accessKeyId, accessKeySecret, instanceId)
 	if err != nil {
@@ -110,12 +153,14 @@ func AliBackup(accessKeyId, accessKeySecret, instanceId, namespace, outputFile s
 		return nil, err
 	}
 
+	var manifest = backup.NewManifest(message.SourceNacos, instanceId)
+
 	// 命名空间
 	namespaces := strings.Split(namespace, ",")
 
 	// 执行备份
 	for _, it := range namespaces {
-		color.Blue(fmt.Sprintf("[Nacos] 备份命名空间: %s", it))
+		logger.With(logger.Fields{"namespace": it}).Info(fmt.Sprintf("[Nacos] 备份命名空间: %s", it))
 		var namespaceId = it
 		if namespaceId == "" {
 			continue
@@ -135,9 +180,11 @@ func AliBackup(accessKeyId, accessKeySecret, instanceId, namespace, outputFile s
 		}
 
 		// Items
+		manifest.Count("namespace", 1)
+		manifest.Count("config", len(items))
 		for _, item := range items {
 			itemDetail, err := nacosClient.GetNacosConfig(namespaceId, *item.Group, *item.DataId)
-			log.Println(fmt.Sprintf("[Nacos 阿里云] %s / %s", namespaceId, *item.DataId))
+			logger.With(logger.Fields{"namespace": namespaceId}).Info(fmt.Sprintf("[Nacos 阿里云] %s / %s", namespaceId, *item.DataId))
 			if err != nil {
 				return nil, err
 			}
@@ -150,101 +197,162 @@ func AliBackup(accessKeyId, accessKeySecret, instanceId, namespace, outputFile s
 	}
 
 	// 备份完成
-	colorPrint := color.New()
-	colorPrint.Add(color.Bold)
-	colorPrint.Add(color.FgGreen)
-	_, err = colorPrint.Println("[Nacos] " + time.Now().Format("2006-01-02 15:04:05") + " 备份完成")
-	if err != nil {
-		return nil, err
-	}
-	// 压缩文件 zip
+	logger.Success("[Nacos] " + time.Now().Format("2006-01-02 15:04:05") + " 备份完成")
+	// 压缩文件
 	if outputFile == "" {
-		outputFile = "nacos.zip"
+		outputFile = "nacos" + archiveFormat.Ext()
 	}
-	err = compress.Zip(*backupDirectory, outputFile, true)
+	err = manifest.Write(*backupDirectory)
 	if err != nil {
 		return nil, err
 	}
-	return &outputFile, nil
+	return encrypt.Archive(*backupDirectory, outputFile, archiveFormat, recipients, true)
 }
 
-func CronBackup(configPath, backupCron, cloudStorageConfig string) error {
-	var values = strings.Split(cloudStorageConfig, ",")
-	if len(values) < 3 {
-		return fmt.Errorf("CloudStorageConfig error")
+// BackupJob 创建备份任务; 每次运行备份任务配置中 nacos 类型的来源 (包含来源目录中的 *.yaml), 有来源失败时返回异常
+func BackupJob(job *config.Job) (scheduler.Func, error) {
+	archiveFormat, err := compress.ParseFormat(job.Archive.Format)
+	if err != nil {
+		return nil, err
 	}
-	var url = values[0]
-	var secretId = values[1]
-	var secretKey = values[2]
-	cosClient, err := storage.NewTencentCOS(url, secretId, secretKey)
+	jobStorage, err := storage.NewJobStorage(job.Storage)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	c := cron.New()
-	_, err = c.AddFunc(backupCron, func() {
+	router, err := job.Notice.Router()
+	if err != nil {
+		return nil, err
+	}
+	if job.SourceDir != "" {
+		logger.Info(fmt.Sprintf("ConfigPath: %s", job.SourceDir))
+	}
+	logger.Info(fmt.Sprintf("S3 Config: %s (policy %s)", strings.Join(storage.Names(jobStorage.Targets), ", "), jobStorage.Policy))
+	if job.Notice.Config != "" {
+		logger.Info(fmt.Sprintf("Notice Config: %s", job.Notice.Describe()))
+	}
+	if jobStorage.Retention.Enabled() {
+		logger.Info(fmt.Sprintf("Retention: %s", jobStorage.Retention))
+	}
+	if jobStorage.Upload.BandwidthLimit > 0 {
+		logger.Info(fmt.Sprintf("Bandwidth Limit: %d B/s", jobStorage.Upload.BandwidthLimit))
+	}
+	return func(ctx context.Context) error {
+		var jobLogger = logger.FromContext(ctx)
 		var dateFormat = time.Now().Format("2006_01_02")
 		var dateTimeFormat = time.Now().Format("2006_01_02_15_04_05")
 		tempDirectory, err := ctl.CreateTempDirectory("cron_nacos", dateTimeFormat)
 		if err != nil {
-			color.Red(fmt.Sprint(err))
-			return
+			return err
 		}
-		err = filepath.Walk(configPath, func(configItemPath string, fi os.FileInfo, errBack error) (err error) {
-			var fileName = fi.Name()
-			if !strings.HasSuffix(fileName, ".yaml") {
-				return nil
-			}
-			// 读取Yaml 文件
-			fileBytes, err := os.ReadFile(configItemPath)
-			if err != nil {
-				return err
+		var events []message.Event
+		var failed []string
+		sources, err := job.ListSources(config.SourceNacos)
+		if err != nil {
+			jobLogger.Error(fmt.Sprint(err))
+			events = append(events, message.BackupEvent(message.SourceNacos, job.SourceDir, "", err))
+			failed = append(failed, err.Error())
+		}
+		for _, source := range sources {
+			if err = ctx.Err(); err != nil {
+				failed = append(failed, err.Error())
+				break
 			}
-			var nacosConfig = struct {
-				Host     string `yaml:"host"`
-				Username string `yaml:"username"`
-				Password string `yaml:"password"`
-
-				AccessKeyId     string `yaml:"accessKeyId"`
-				AccessKeySecret string `yaml:"accessKeySecret"`
-				InstanceId      string `yaml:"instanceId"`
-				Namespace       string `yaml:"namespace"`
-			}{}
-			err = yaml.Unmarshal(fileBytes, &nacosConfig)
+			var sourceLogger = jobLogger.With(logger.Fields{"host": source.Host})
+			result, err := cronBackupSource(ctx, source, jobStorage, *tempDirectory, dateFormat, dateTimeFormat, archiveFormat, job.Archive)
 			if err != nil {
-				return err
+				sourceLogger.Error(fmt.Sprint(err))
+				events = append(events, message.BackupEvent(message.SourceNacos, source.Name, "", err))
+				failed = append(failed, source.Name+": "+err.Error())
+				continue
 			}
-			// 备份任务
-			var outFileName = path.Base(fileName) + "_" + dateTimeFormat + ".zip"
-			var outputFile = path.Join(*tempDirectory, outFileName)
-			var backupZipFile *string
-			if nacosConfig.InstanceId == "" {
-				backupZipFile, err = Backup(nacosConfig.Host, nacosConfig.Username, nacosConfig.Password, outputFile)
+			events = append(events, message.ReplicaBackupEvent(message.SourceNacos, source.Name, result.String(), result.Degraded(), nil))
+			// 清理过期备份 (只清理本次上传成功的目标), 失败不影响本次备份结果
+			for _, target := range result.Uploaded(jobStorage.Targets) {
+				_, err = storage.Prune(target, "nacos/", source.Name, jobStorage.Retention)
 				if err != nil {
-					return err
-				}
-			} else {
-				backupZipFile, err = AliBackup(nacosConfig.AccessKeyId, nacosConfig.AccessKeySecret, nacosConfig.InstanceId, nacosConfig.Namespace, outputFile)
-				if err != nil {
-					return err
+					sourceLogger.Error(fmt.Sprint(err))
 				}
 			}
-			_, err = cosClient.Put(*backupZipFile, "nacos/"+dateFormat+"/"+outFileName)
+		}
+		// 消息通知
+		if router != nil && len(events) > 0 {
+			_, err = router.Dispatch("Nacos 备份", events)
 			if err != nil {
-				return err
+				jobLogger.Error(fmt.Sprint(err))
 			}
-			return nil
-		})
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("backup failed: %s", strings.Join(failed, "; "))
+		}
+		return nil
+	}, nil
+}
+
+// CronBackup 定时备份 (见 BackupJob); 收到 SIGTERM 时等待运行中的备份结束; runNow 时启动后立即运行一次
+func CronBackup(job *config.Job, listen string, runNow bool) error {
+	run, err := BackupJob(job)
+	if err != nil {
+		return err
+	}
+	var name = job.Name
+	if name == "" {
+		name = config.SourceNacos
+	}
+	var jobScheduler = scheduler.New(nil)
+	err = jobScheduler.Add(name, job.Schedule, run)
+	if err != nil {
+		return err
+	}
+	if listen != "" {
+		err = jobScheduler.Listen(listen)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Info(fmt.Sprintf("Cron (%s) Start Success ...", job.Schedule))
+	if runNow {
+		err = jobScheduler.Trigger(name)
 		if err != nil {
-			color.Red(fmt.Sprint(err))
+			return err
 		}
-	})
+	}
+	jobScheduler.Serve(scheduler.DefaultStopTimeout)
+	return nil
+}
+
+// cronBackupSource 备份单个来源并上传到所有目标
+func cronBackupSource(ctx context.Context, source config.Source, jobStorage *storage.JobStorage, tempDirectory, dateFormat, dateTimeFormat string, archiveFormat compress.Format, archive config.Archive) (*storage.ReplicaResult, error) {
+	var outFileName = source.Name + "_" + dateTimeFormat + archiveFormat.Ext()
+	var outputFile = path.Join(tempDirectory, outFileName)
+	var backupZipFile *string
+	var err error
+	if source.InstanceId == "" {
+		backupZipFile, err = Backup(source.Host, source.Username, source.Password, outputFile, archive.Format, archive.Recipients, ctl.OutputTable)
+	} else {
+		backupZipFile, err = AliBackup(source.AccessKeyId, source.AccessKeySecret, source.InstanceId, source.Namespace, outputFile, archive.Format, archive.Recipients)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var options = jobStorage.Upload
+	options.Progress = storage.PrintProgress(path.Base(*backupZipFile))
+	options.Put.Tags = map[string]string{"tool": message.SourceNacos, "source": source.Host}
+	if source.InstanceId != "" {
+		options.Put.Tags["source"] = source.InstanceId
+	}
+	result := storage.Replicate(ctx, jobStorage.Targets, jobStorage.Policy, *backupZipFile, "nacos/"+dateFormat+"/"+path.Base(*backupZipFile), options)
+	return result, result.Err()
+}
+
+// Verify 校验备份归档; file 本地文件或下载地址, identities 解密密钥 (.age 加密归档), output 输出格式 (table|json|yaml|csv|markdown)
+func Verify(file string, identities []string, output string) error {
+	verification, err := backup.VerifyFile(file, identities)
+	if err != nil {
+		return err
+	}
+	err = verification.Print(output)
 	if err != nil {
 		return err
 	}
-	color.Blue(fmt.Sprintf("Cron (%s) Start Success ...", backupCron))
-	color.Blue(fmt.Sprintf("ConfigPath: %s", configPath))
-	color.Blue(fmt.Sprintf("S3 Config: %s", cloudStorageConfig))
-	c.Start()
-	select {}
+	return verification.Err()
 }
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/lib.v3/secret"
+	"github.com/longyuan/scheduler.v3/console"
+	"github.com/spf13/cobra"
+)
+
+func Scheduler() []*cobra.Command {
+	var runCmd = &cobra.Command{
+		Use:     "run",
+		Short:   "Run Scheduler Daemon (Nacos, Gitlab, Kubernetes Backups and Domain Checks)",
+		Example: "run -c scheduler.yaml",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, err := cmd.Flags().GetString("config")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			if configPath == "" {
+				logger.Error("Config Required")
+				return
+			}
+			listen, err := cmd.Flags().GetString("listen")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			token, err := cmd.Flags().GetString("token")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			// 支持密钥引用 (env:, file:, vault:)
+			token, err = secret.Resolve(token)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			runNow, err := cmd.Flags().GetBool("run-now")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Run(configPath, listen, token, runNow)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	runCmd.Flags().StringP("config", "c", "", "Scheduler Config YAML/JSON (history, stopTimeout, listen, jobs)")
+	runCmd.Flags().String("listen", "", "Status API Address (e.g. 127.0.0.1:9100) Serving /healthz, /jobs and /metrics; Overrides listen In Config")
+	runCmd.Flags().String("token", "", "Bearer Token Required By POST /jobs/{name}/run, or Secret Reference (env:NAME, file:PATH, vault:NAME); Overrides token In Config and LONGYUAN_API_TOKEN")
+	runCmd.Flags().Bool("run-now", false, "Run All Jobs Once Immediately, Then Follow The Schedules")
+
+	var historyCmd = &cobra.Command{
+		Use:     "history",
+		Short:   "Job Run History",
+		Example: "history -c scheduler.yaml --job nacos-prod",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, err := cmd.Flags().GetString("config")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			if configPath == "" {
+				logger.Error("Config Required")
+				return
+			}
+			name, err := cmd.Flags().GetString("job")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			limit, err := cmd.Flags().GetInt("limit")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.History(configPath, name, limit, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	historyCmd.Flags().StringP("config", "c", "", "Scheduler Config YAML/JSON")
+	historyCmd.Flags().String("job", "", "Job Name")
+	historyCmd.Flags().Int("limit", 10, "Runs Per Job")
+	historyCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
+
+	var triggerCmd = &cobra.Command{
+		Use:     "trigger",
+		Short:   "Run A Job Of The Running Scheduler Daemon Now",
+		Example: "trigger -c scheduler.yaml --job nacos-prod",
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath, err := cmd.Flags().GetString("config")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			address, err := cmd.Flags().GetString("address")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			name, err := cmd.Flags().GetString("job")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			if name == "" {
+				logger.Error("Job Required")
+				return
+			}
+			token, err := cmd.Flags().GetString("token")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			// 支持密钥引用 (env:, file:, vault:)
+			token, err = secret.Resolve(token)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			noWait, err := cmd.Flags().GetBool("no-wait")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Trigger(configPath, address, token, name, !noWait)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	triggerCmd.Flags().StringP("config", "c", "", "Scheduler Config YAML/JSON (uses listen)")
+	triggerCmd.Flags().String("address", "", "Status API Address (e.g. 127.0.0.1:9100); Overrides listen In Config")
+	triggerCmd.Flags().String("token", "", "Bearer Token or Secret Reference (env:NAME, file:PATH, vault:NAME); Overrides token In Config and LONGYUAN_API_TOKEN")
+	triggerCmd.Flags().String("job", "", "Job Name")
+	triggerCmd.Flags().Bool("no-wait", false, "Return Without Waiting For The Run Result")
+
+	return []*cobra.Command{
+		runCmd,
+		historyCmd,
+		triggerCmd,
+	}
+}
@@ -0,0 +1,258 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	domain "github.com/longyuan/domain.v3/console"
+	gitlab "github.com/longyuan/gitlab.v3/console"
+	kubernetes "github.com/longyuan/kubernetes.v3/console"
+	"github.com/longyuan/lib.v3/config"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/lib.v3/scheduler"
+	"github.com/longyuan/lib.v3/state"
+	nacos "github.com/longyuan/nacos.v3/console"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// runners 每种来源类型的任务
+var runners = map[string]func(job *config.Job) (scheduler.Func, error){
+	config.SourceNacos:      nacos.BackupJob,
+	config.SourceGitlab:     gitlab.BackupJob,
+	config.SourceKubernetes: kubernetes.BackupJob,
+	config.SourceDomain:     domain.CheckJob,
+}
+
+// newJob 创建任务; 任务包含多种来源时依次运行每种来源, 异常包含每种来源的异常
+func newJob(job *config.Job) (scheduler.Func, error) {
+	var types = job.SourceTypes()
+	var runs []scheduler.Func
+	for _, sourceType := range types {
+		run, err := runners[sourceType](job)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %s: %w", job.Name, sourceType, err)
+		}
+		runs = append(runs, run)
+	}
+	return func(ctx context.Context) error {
+		var failed []string
+		for index, run := range runs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			err := run(ctx)
+			if err != nil {
+				failed = append(failed, types[index]+": "+err.Error())
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("%s", strings.Join(failed, "; "))
+		}
+		return nil
+	}, nil
+}
+
+// newScheduler 根据调度服务配置创建调度
+func newScheduler(daemon *config.Daemon) (*scheduler.Scheduler, error) {
+	var store *state.Store
+	if daemon.History != "" {
+		var err error
+		store, err = state.Open(daemon.History)
+		if err != nil {
+			return nil, err
+		}
+	}
+	history, err := scheduler.NewHistory(store, daemon.HistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	var jobScheduler = scheduler.New(history)
+	for index := range daemon.Jobs {
+		var job = &daemon.Jobs[index]
+		logger.Info(fmt.Sprintf("[Scheduler] 任务 %s (%s): %s", job.Name, job.Schedule, strings.Join(job.SourceTypes(), ", ")))
+		run, err := newJob(job)
+		if err != nil {
+			return nil, err
+		}
+		err = jobScheduler.Add(job.Name, job.Schedule, run)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobScheduler, nil
+}
+
+// Run 运行调度服务; 一个进程调度配置中的所有任务, 收到 SIGTERM 时等待运行中的任务结束 (超过 stopTimeout 后取消)
+// listen, token 不为空时覆盖配置中的状态接口地址和触发任务的令牌, runNow 时启动后立即运行所有任务一次
+func Run(configPath, listen, token string, runNow bool) error {
+	daemon, err := config.LoadDaemon(configPath)
+	if err != nil {
+		return err
+	}
+	jobScheduler, err := newScheduler(daemon)
+	if err != nil {
+		return err
+	}
+	if listen == "" {
+		listen = daemon.Listen
+	}
+	if token == "" {
+		token, err = daemon.APIToken()
+		if err != nil {
+			return err
+		}
+	}
+	jobScheduler.SetToken(token)
+	if listen != "" {
+		err = jobScheduler.Listen(listen)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Success(fmt.Sprintf("Scheduler Start Success, %d Jobs ...", len(daemon.Jobs)))
+	if daemon.History != "" {
+		logger.Info(fmt.Sprintf("History: %s", daemon.History))
+	}
+	if runNow {
+		for _, job := range daemon.Jobs {
+			err = jobScheduler.Trigger(job.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	jobScheduler.Serve(daemon.Timeout())
+	return nil
+}
+
+// historyRow 运行记录
+type historyRow struct {
+	Job      string           `json:"job" title:"任务"`
+	Start    time.Time        `json:"start" title:"开始时间"`
+	End      *time.Time       `json:"end" title:"结束时间"`
+	Duration string           `json:"duration" title:"耗时"`
+	Status   scheduler.Status `json:"status" title:"状态"`
+	Error    string           `json:"error" title:"错误"`
+}
+
+// History 输出运行记录; name 为空时输出所有任务, limit 每个任务最多输出的记录数量, output 输出格式 (table|json|yaml|csv|markdown)
+func History(configPath, name string, limit int, output string) error {
+	daemon, err := config.LoadDaemon(configPath)
+	if err != nil {
+		return err
+	}
+	if daemon.History == "" {
+		return fmt.Errorf("history not configured: %s", configPath)
+	}
+	store, err := state.Open(daemon.History)
+	if err != nil {
+		return err
+	}
+	history, err := scheduler.NewHistory(store, daemon.HistoryLimit)
+	if err != nil {
+		return err
+	}
+	var rows []historyRow
+	for _, job := range daemon.Jobs {
+		if name != "" && job.Name != name {
+			continue
+		}
+		for index, run := range history.List(job.Name) {
+			if limit > 0 && index >= limit {
+				break
+			}
+			var row = historyRow{
+				Job: run.Job, Start: run.Start, Duration: run.Duration().Round(time.Second).String(),
+				Status: run.Status, Error: run.Error,
+			}
+			if !run.End.IsZero() {
+				var end = run.End
+				row.End = &end
+			}
+			rows = append(rows, row)
+		}
+	}
+	return ctl.Print(output, rows)
+}
+
+// Trigger 通过调度服务的状态接口立即运行任务; address, token 为空时使用配置中的 listen 和 token (或环境变量 LONGYUAN_API_TOKEN).
+// 任务的输出见调度服务日志; wait 时等待任务结束并输出结果, 任务失败时返回任务的异常
+func Trigger(configPath, address, token, name string, wait bool) error {
+	if configPath != "" && (address == "" || token == "") {
+		daemon, err := config.LoadDaemon(configPath)
+		if err != nil {
+			return err
+		}
+		if address == "" {
+			if daemon.Listen == "" {
+				return fmt.Errorf("listen not configured: %s", configPath)
+			}
+			address = daemon.Listen
+		}
+		if token == "" {
+			token, err = daemon.APIToken()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if address == "" {
+		return fmt.Errorf("config or address required")
+	}
+	if token == "" {
+		token = os.Getenv(scheduler.TokenEnv)
+	}
+	if strings.HasPrefix(address, ":") {
+		address = "127.0.0.1" + address
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	var triggerURL = strings.TrimSuffix(address, "/") + "/jobs/" + url.PathEscape(name) + "/run"
+	if !wait {
+		triggerURL += "?wait=false"
+	}
+	logger.Info(fmt.Sprintf("[Scheduler] 触发任务 %s: %s", name, triggerURL))
+	request, err := http.NewRequest(http.MethodPost, triggerURL, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	var contentType = response.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/json") {
+		if response.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(body)))
+		}
+		logger.Success(fmt.Sprintf("[Scheduler] %s: %s", name, strings.TrimSpace(string(body))))
+		return nil
+	}
+	var run scheduler.Run
+	err = json.Unmarshal(body, &run)
+	if err != nil {
+		return err
+	}
+	var result = fmt.Sprintf("[Scheduler] %s: %s (%s)", name, run.Status, run.Duration().Round(time.Second))
+	if run.Status != scheduler.StatusSuccess {
+		logger.Error(result)
+		return fmt.Errorf("%s", run.Error)
+	}
+	logger.Success(result)
+	return nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/scheduler.v3/cmd"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	var rootCmd = &cobra.Command{Use: "schctl"}
+	for _, it := range cmd.Scheduler() {
+		rootCmd.AddCommand(it)
+	}
+	logger.AddFlags(rootCmd, "scheduler")
+	err := rootCmd.Execute()
+	if err != nil {
+		return
+	}
+}
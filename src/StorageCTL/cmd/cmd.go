@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/storage.v3/console"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+const cloudStorageUsage = "CloudStorage Config (cos|s3|oss|file)://..., URL,SecretId,SecretKey or Secret Reference (env:NAME, file:PATH, vault:NAME)"
+
+func Storage() []*cobra.Command {
+	var lsCmd = &cobra.Command{
+		Use:     "ls",
+		Short:   "List Objects or Backups",
+		Example: "ls --cloud-storage cos://[SecretId]:[SecretKey]@[Bucket].cos.ap-shanghai.myqcloud.com --tool nacos --name prod.yaml --latest",
+		Run: func(cmd *cobra.Command, args []string) {
+			cloudStorage, err := cmd.Flags().GetString("cloud-storage")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			prefix, err := cmd.Flags().GetString("prefix")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			tool, err := cmd.Flags().GetString("tool")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			latest, err := cmd.Flags().GetBool("latest")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Ls(cloudStorage, prefix, tool, name, latest, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	lsCmd.Flags().String("cloud-storage", "", cloudStorageUsage)
+	lsCmd.Flags().StringP("prefix", "p", "", "Object Prefix")
+	lsCmd.Flags().String("tool", "", "Backup Tool (nacos|gitlab|kubernetes)")
+	lsCmd.Flags().String("name", "", "Backup Config File Name (e.g. prod.yaml)")
+	lsCmd.Flags().Bool("latest", false, "Only Latest Backup Of Each Config File")
+	lsCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
+
+	var getCmd = &cobra.Command{
+		Use:     "get",
+		Short:   "Download Object or Latest Backup",
+		Example: "get --cloud-storage file:///data/backup --tool nacos --name prod.yaml -o ./",
+		Run: func(cmd *cobra.Command, args []string) {
+			cloudStorage, err := cmd.Flags().GetString("cloud-storage")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			key, err := cmd.Flags().GetString("key")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			tool, err := cmd.Flags().GetString("tool")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			localPath, err := console.Get(cloudStorage, key, tool, name, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			logger.Success(fmt.Sprintf("Download Success: %s", *localPath))
+		},
+	}
+	getCmd.Flags().String("cloud-storage", "", cloudStorageUsage)
+	getCmd.Flags().StringP("key", "k", "", "Object Key")
+	getCmd.Flags().String("tool", "", "Backup Tool (nacos|gitlab|kubernetes)")
+	getCmd.Flags().String("name", "", "Backup Config File Name (e.g. prod.yaml)")
+	getCmd.Flags().StringP("output", "o", "", "Output File or Directory")
+
+	var rmCmd = &cobra.Command{
+		Use:     "rm",
+		Short:   "Delete Objects",
+		Example: "rm --cloud-storage file:///data/backup -k nacos/2023_05_01/prod.yaml_2023_05_01_00_00_00.zip",
+		Run: func(cmd *cobra.Command, args []string) {
+			cloudStorage, err := cmd.Flags().GetString("cloud-storage")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			keys, err := cmd.Flags().GetStringSlice("key")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			if len(keys) == 0 {
+				logger.Error("Key Required")
+				return
+			}
+			err = console.Rm(cloudStorage, keys)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	rmCmd.Flags().String("cloud-storage", "", cloudStorageUsage)
+	rmCmd.Flags().StringSliceP("key", "k", nil, "Object Key")
+
+	var duCmd = &cobra.Command{
+		Use:     "du",
+		Short:   "Storage Usage",
+		Example: "du --cloud-storage file:///data/backup -p nacos/ --depth 2",
+		Run: func(cmd *cobra.Command, args []string) {
+			cloudStorage, err := cmd.Flags().GetString("cloud-storage")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			prefix, err := cmd.Flags().GetString("prefix")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			depth, err := cmd.Flags().GetInt("depth")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Du(cloudStorage, prefix, depth, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	duCmd.Flags().String("cloud-storage", "", cloudStorageUsage)
+	duCmd.Flags().StringP("prefix", "p", "", "Object Prefix")
+	duCmd.Flags().Int("depth", 1, "Directory Depth (1: tool, 2: tool/date)")
+	duCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
+
+	var verifyCmd = &cobra.Command{
+		Use:     "verify",
+		Short:   "Verify Backup Archive (manifest.json SHA-256)",
+		Example: "verify --cloud-storage file:///data/backup --tool kubernetes --name prod.yaml --identity key.txt",
+		Run: func(cmd *cobra.Command, args []string) {
+			cloudStorage, err := cmd.Flags().GetString("cloud-storage")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			key, err := cmd.Flags().GetString("key")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			tool, err := cmd.Flags().GetString("tool")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			identities, err := cmd.Flags().GetStringSlice("identity")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.Verify(cloudStorage, key, tool, name, identities, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				os.Exit(1)
+			}
+		},
+	}
+	verifyCmd.Flags().String("cloud-storage", "", cloudStorageUsage)
+	verifyCmd.Flags().StringP("key", "k", "", "Object Key")
+	verifyCmd.Flags().String("tool", "", "Backup Tool (nacos|gitlab|kubernetes)")
+	verifyCmd.Flags().String("name", "", "Backup Config File Name (e.g. prod.yaml)")
+	verifyCmd.Flags().StringSlice("identity", nil, "Decrypt Identity (AGE-SECRET-KEY-1... Private Key, Identity File or pass:Passphrase; pass:env:NAME or pass:file:PATH Reads The Passphrase From Env or File)")
+	verifyCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
+
+	return []*cobra.Command{lsCmd, getCmd, rmCmd, duCmd, verifyCmd}
+}
+
+func Vault() *cobra.Command {
+	var vaultCmd = &cobra.Command{
+		Use:   "vault",
+		Short: "Local Encrypted Secret Vault (referenced as vault:NAME in configs)",
+	}
+	vaultCmd.PersistentFlags().String("vault", "", "Vault File (default $LONGYUAN_VAULT or ~/.longyuan/vault.age)")
+	vaultCmd.PersistentFlags().String("vault-key", "", "Vault Key (pass:Passphrase, AGE-SECRET-KEY-1... or Identity File; default $LONGYUAN_VAULT_KEY)")
+
+	var setCmd = &cobra.Command{
+		Use:     "set",
+		Short:   "Set Secret (value from --value or stdin)",
+		Example: "echo $GITLAB_TOKEN | vault set gitlab-token",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				logger.Error("Name Required")
+				return
+			}
+			vaultPath, err := cmd.Flags().GetString("vault")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			vaultKey, err := cmd.Flags().GetString("vault-key")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			value, err := cmd.Flags().GetString("value")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.VaultSet(vaultPath, vaultKey, args[0], value)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	setCmd.Flags().String("value", "", "Secret Value (visible in shell history, prefer stdin)")
+
+	var lsCmd = &cobra.Command{
+		Use:     "ls",
+		Short:   "List Secret Names",
+		Example: "vault ls",
+		Run: func(cmd *cobra.Command, args []string) {
+			vaultPath, err := cmd.Flags().GetString("vault")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			vaultKey, err := cmd.Flags().GetString("vault-key")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.VaultLs(vaultPath, vaultKey, output)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+	lsCmd.Flags().String("output", ctl.OutputTable, ctl.OutputUsage)
+
+	var rmCmd = &cobra.Command{
+		Use:     "rm",
+		Short:   "Delete Secrets",
+		Example: "vault rm gitlab-token",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				logger.Error("Name Required")
+				return
+			}
+			vaultPath, err := cmd.Flags().GetString("vault")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			vaultKey, err := cmd.Flags().GetString("vault-key")
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+			err = console.VaultRm(vaultPath, vaultKey, args)
+			if err != nil {
+				logger.Error(fmt.Sprint(err))
+				return
+			}
+		},
+	}
+
+	vaultCmd.AddCommand(setCmd, lsCmd, rmCmd)
+	return vaultCmd
+}
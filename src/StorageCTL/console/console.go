@@ -0,0 +1,230 @@
+package console
+
+import (
+	"fmt"
+	"github.com/longyuan/lib.v3/backup"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/storage.v3/storage"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// size 对象大小 (字节), table/csv/markdown 中格式化输出 (如 1.50 MB)
+type size int64
+
+func (value size) String() string {
+	return storage.SizeFormat(int64(value))
+}
+
+// objectRow 对象
+type objectRow struct {
+	Key          string    `json:"key" title:"路径"`
+	Size         size      `json:"size" title:"大小"`
+	LastModified time.Time `json:"lastModified" title:"修改时间"`
+}
+
+// backupRow 备份
+type backupRow struct {
+	Tool string    `json:"tool" title:"工具"`
+	Name string    `json:"name" title:"配置文件"`
+	Time time.Time `json:"time" title:"备份时间"`
+	Size size      `json:"size" title:"大小"`
+	Key  string    `json:"key" title:"路径"`
+}
+
+// duRow 目录用量
+type duRow struct {
+	Directory string `json:"directory" title:"目录"`
+	Objects   int    `json:"objects" title:"对象数量"`
+	Size      size   `json:"size" title:"大小"`
+}
+
+// Ls 列出对象; 指定 tool 时按备份路径格式列出备份 (最新的在前), latest 只输出每个配置文件最新的备份, output 输出格式 (table|json|yaml|csv|markdown)
+func Ls(cloudStorageConfig, prefix, tool, name string, latest bool, output string) error {
+	output, err := ctl.ParseOutput(output)
+	if err != nil {
+		return err
+	}
+	cloudStorage, err := storage.NewCloudStorage(cloudStorageConfig)
+	if err != nil {
+		return err
+	}
+	if tool == "" && name == "" {
+		objects, err := cloudStorage.List(prefix)
+		if err != nil {
+			return err
+		}
+		var rows []objectRow
+		for _, object := range objects {
+			rows = append(rows, objectRow{Key: object.Key, Size: size(object.Size), LastModified: object.LastModified.Local()})
+		}
+		err = ctl.Print(output, rows)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("对象数量: %d", len(objects)))
+		return nil
+	}
+	backups, err := storage.ListBackups(cloudStorage, tool, name)
+	if err != nil {
+		return err
+	}
+	var listed = map[string]bool{}
+	var rows []backupRow
+	for _, item := range backups {
+		if latest {
+			if listed[item.Tool+"/"+item.Name] {
+				continue
+			}
+			listed[item.Tool+"/"+item.Name] = true
+		}
+		rows = append(rows, backupRow{Tool: item.Tool, Name: item.Name, Time: item.Time, Size: size(item.Size), Key: item.Key})
+	}
+	err = ctl.Print(output, rows)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("备份数量: %d", len(rows)))
+	return nil
+}
+
+// Get 下载对象; key 为空时下载 tool、name 最新的备份, output 为空时保存到当前目录
+func Get(cloudStorageConfig, key, tool, name, output string) (*string, error) {
+	cloudStorage, err := storage.NewCloudStorage(cloudStorageConfig)
+	if err != nil {
+		return nil, err
+	}
+	key, err = resolveKey(cloudStorage, key, tool, name)
+	if err != nil {
+		return nil, err
+	}
+	var localPath = output
+	if localPath == "" {
+		localPath = path.Base(key)
+	} else if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		localPath = filepath.Join(localPath, path.Base(key))
+	}
+	err = cloudStorage.Get(key, localPath)
+	if err != nil {
+		return nil, err
+	}
+	return &localPath, nil
+}
+
+// Rm 删除对象
+func Rm(cloudStorageConfig string, keys []string) error {
+	cloudStorage, err := storage.NewCloudStorage(cloudStorageConfig)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		// 删除前确认对象存在, 避免误删后才发现路径错误
+		_, err = cloudStorage.Stat(key)
+		if err != nil {
+			return err
+		}
+		err = cloudStorage.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Du 统计存储用量; 按路径前 depth 级目录汇总 (如 2: nacos/2023_05_01), table/markdown 格式时最后一行为合计
+func Du(cloudStorageConfig, prefix string, depth int, output string) error {
+	output, err := ctl.ParseOutput(output)
+	if err != nil {
+		return err
+	}
+	cloudStorage, err := storage.NewCloudStorage(cloudStorageConfig)
+	if err != nil {
+		return err
+	}
+	objects, err := cloudStorage.List(prefix)
+	if err != nil {
+		return err
+	}
+	var counts = map[string]int{}
+	var sizes = map[string]int64{}
+	var total int64
+	for _, object := range objects {
+		var values = strings.Split(object.Key, "/")
+		if depth > 0 && len(values) > depth {
+			values = values[:depth]
+		} else {
+			values = values[:len(values)-1]
+		}
+		var group = strings.Join(values, "/") + "/"
+		counts[group]++
+		sizes[group] += object.Size
+		total += object.Size
+	}
+	var groups []string
+	for group := range counts {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	var rows []duRow
+	for _, group := range groups {
+		rows = append(rows, duRow{Directory: group, Objects: counts[group], Size: size(sizes[group])})
+	}
+	if output == ctl.OutputTable || output == ctl.OutputMarkdown {
+		rows = append(rows, duRow{Directory: "合计", Objects: len(objects), Size: size(total)})
+	}
+	return ctl.Print(output, rows)
+}
+
+// Verify 下载并校验备份归档; key 为空时校验 tool、name 最新的备份, identities 解密密钥 (.age 加密归档), output 输出格式 (table|json|yaml|csv|markdown)
+func Verify(cloudStorageConfig, key, tool, name string, identities []string, output string) error {
+	cloudStorage, err := storage.NewCloudStorage(cloudStorageConfig)
+	if err != nil {
+		return err
+	}
+	key, err = resolveKey(cloudStorage, key, tool, name)
+	if err != nil {
+		return err
+	}
+	tempDirectory, err := os.MkdirTemp("", "sctl_verify_*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDirectory)
+	}()
+	var localPath = filepath.Join(tempDirectory, path.Base(key))
+	err = cloudStorage.Get(key, localPath)
+	if err != nil {
+		return err
+	}
+	verification, err := backup.VerifyFile(localPath, identities)
+	if err != nil {
+		return err
+	}
+	err = verification.Print(output)
+	if err != nil {
+		return err
+	}
+	return verification.Err()
+}
+
+// resolveKey key 为空时返回 tool、name 最新的备份路径
+func resolveKey(cloudStorage storage.CloudStorage, key, tool, name string) (string, error) {
+	if key != "" {
+		return key, nil
+	}
+	if tool == "" || name == "" {
+		return "", fmt.Errorf("key or tool and name required")
+	}
+	latest, err := storage.LatestBackup(cloudStorage, tool, name)
+	if err != nil {
+		return "", err
+	}
+	logger.Info(fmt.Sprintf("Latest Backup: %s (%s)", latest.Key, latest.Time.Format("2006-01-02 15:04:05")))
+	return latest.Key, nil
+}
@@ -0,0 +1,91 @@
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/longyuan/lib.v3/ctl"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/lib.v3/secret"
+	"io"
+	"os"
+	"strings"
+)
+
+// openVault 打开密钥库; vaultPath、key 为空时使用环境变量 LONGYUAN_VAULT、LONGYUAN_VAULT_KEY
+func openVault(vaultPath, key string) (*secret.Vault, error) {
+	if vaultPath == "" {
+		vaultPath = secret.VaultPath()
+	}
+	if key == "" {
+		key = os.Getenv(secret.VaultKeyEnv)
+	}
+	return secret.OpenVault(vaultPath, key)
+}
+
+// VaultSet 写入密钥; value 为空时从标准输入读取 (第一行)
+func VaultSet(vaultPath, key, name, value string) error {
+	vault, err := openVault(vaultPath, key)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		value, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		value = strings.TrimRight(value, "\r\n")
+	}
+	if value == "" {
+		return fmt.Errorf("secret value required")
+	}
+	err = vault.Set(name, value)
+	if err != nil {
+		return err
+	}
+	logger.Success(fmt.Sprintf("Vault %s: %s saved, reference: %s%s", vault.Path(), name, secret.PrefixVault, name))
+	return nil
+}
+
+// vaultRow 密钥名称和引用
+type vaultRow struct {
+	Name      string `json:"name" title:"名称"`
+	Reference string `json:"reference" title:"引用"`
+}
+
+// VaultLs 列出密钥名称 (不输出密钥), output 输出格式 (table|json|yaml|csv|markdown)
+func VaultLs(vaultPath, key, output string) error {
+	vault, err := openVault(vaultPath, key)
+	if err != nil {
+		return err
+	}
+	var rows []vaultRow
+	for _, name := range vault.Names() {
+		rows = append(rows, vaultRow{Name: name, Reference: secret.PrefixVault + name})
+	}
+	err = ctl.Print(output, rows)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Vault: %s", vault.Path()))
+	return nil
+}
+
+// VaultRm 删除密钥
+func VaultRm(vaultPath, key string, names []string) error {
+	vault, err := openVault(vaultPath, key)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		ok, err := vault.Delete(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			logger.Warn(fmt.Sprintf("Vault: %s not found", name))
+			continue
+		}
+		logger.Success(fmt.Sprintf("Vault: %s deleted", name))
+	}
+	return nil
+}
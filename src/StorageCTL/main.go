@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/longyuan/storage.v3/cmd"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	var rootCmd = &cobra.Command{Use: "sctl"}
+	for _, it := range cmd.Storage() {
+		rootCmd.AddCommand(it)
+	}
+	rootCmd.AddCommand(cmd.Vault())
+	logger.AddFlags(rootCmd, "storage")
+	err := rootCmd.Execute()
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup 定时备份上传的对象; 路径格式 <工具>/<YYYY_MM_DD>/<配置文件名>_<YYYY_MM_DD_HH_MM_SS><后缀>
+type Backup struct {
+	Object
+	// Tool 备份工具 (nacos, gitlab, kubernetes)
+	Tool string
+	// Name 配置文件名 (如 prod.yaml)
+	Name string
+	// Time 备份时间
+	Time time.Time
+}
+
+// ParseBackup 解析备份对象路径, 不符合备份路径格式时返回 false
+func ParseBackup(object Object) (*Backup, bool) {
+	var values = strings.Split(object.Key, "/")
+	if len(values) != 3 {
+		return nil, false
+	}
+	if _, err := time.Parse("2006_01_02", values[1]); err != nil {
+		return nil, false
+	}
+	name, backupTime, ok := BackupName(values[2])
+	if !ok {
+		return nil, false
+	}
+	return &Backup{Object: object, Tool: values[0], Name: name, Time: backupTime}, true
+}
+
+// ListBackups 列出备份, 最新的在前; tool 备份工具, name 配置文件名, 为空时不过滤
+func ListBackups(cloudStorage CloudStorage, tool, name string) ([]Backup, error) {
+	var prefix string
+	if tool != "" {
+		prefix = strings.TrimSuffix(tool, "/") + "/"
+	}
+	objects, err := cloudStorage.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var backups []Backup
+	for _, object := range objects {
+		backup, ok := ParseBackup(object)
+		if !ok || (name != "" && backup.Name != name) {
+			continue
+		}
+		backups = append(backups, *backup)
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// LatestBackup 最新的备份; 如 nacos 工具 prod.yaml 最新的备份
+func LatestBackup(cloudStorage CloudStorage, tool, name string) (*Backup, error) {
+	backups, err := ListBackups(cloudStorage, tool, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("backup not found: %s %s: %w", tool, name, ErrNotExist)
+	}
+	return &backups[0], nil
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLatestBackup(test *testing.T) {
+	cloudStorage, err := NewLocal(test.TempDir())
+	if err != nil {
+		test.Fatal(err)
+	}
+	for _, key := range []string{
+		"nacos/2023_05_01/prod.yaml_2023_05_01_00_00_00.zip",
+		"nacos/2023_05_02/prod.yaml_2023_05_02_00_00_00.zip.age",
+		"nacos/2023_05_03/test.yaml_2023_05_03_00_00_00.zip",
+		"nacos/readme.txt",
+		"gitlab/2023_05_04/prod.yaml_2023_05_04_00_00_00.tar.gz",
+	} {
+		if _, err = cloudStorage.PutReader(strings.NewReader(key), key); err != nil {
+			test.Fatal(err)
+		}
+	}
+	backups, err := ListBackups(cloudStorage, "nacos", "")
+	if err != nil || len(backups) != 3 || backups[0].Name != "test.yaml" || backups[2].Tool != "nacos" {
+		test.Fatal(backups, err)
+	}
+	latest, err := LatestBackup(cloudStorage, "nacos", "prod.yaml")
+	if err != nil || latest.Key != "nacos/2023_05_02/prod.yaml_2023_05_02_00_00_00.zip.age" || latest.Time.Day() != 2 {
+		test.Fatal(latest, err)
+	}
+	if _, err = LatestBackup(cloudStorage, "kubernetes", "prod.yaml"); !errors.Is(err, ErrNotExist) {
+		test.Fatal(err)
+	}
+	if _, ok := ParseBackup(Object{Key: "nacos/latest/prod.yaml_2023_05_01_00_00_00.zip"}); ok {
+		test.Fatal("date directory required")
+	}
+}
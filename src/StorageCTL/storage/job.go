@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"github.com/longyuan/lib.v3/config"
+)
+
+// JobStorage 任务配置 (config.Storage) 对应的备份目标、成功策略、保留策略和上传配置
+type JobStorage struct {
+	Targets   []Target
+	Policy    Policy
+	Retention Retention
+	Upload    UploadOptions
+}
+
+// NewJobStorage 根据任务配置创建存储
+func NewJobStorage(value config.Storage) (*JobStorage, error) {
+	policy, err := ParsePolicy(value.Policy)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := NewTargets(value.Targets)
+	if err != nil {
+		return nil, err
+	}
+	bandwidthLimit, err := ParseSize(value.Upload.BandwidthLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &JobStorage{
+		Targets: targets,
+		Policy:  policy,
+		Retention: Retention{
+			KeepLast:    value.Retention.KeepLast,
+			KeepDaily:   value.Retention.KeepDaily,
+			KeepWeekly:  value.Retention.KeepWeekly,
+			KeepMonthly: value.Retention.KeepMonthly,
+			DryRun:      value.Retention.DryRun,
+		},
+		Upload: UploadOptions{Concurrency: value.Upload.Concurrency, BandwidthLimit: bandwidthLimit},
+	}, nil
+}
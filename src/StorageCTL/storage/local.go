@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localPath 对象对应的本地路径; 不允许跳出根目录
+func (c *LocalStorage) localPath(cloudPath string) (string, error) {
+	var localPath = filepath.Join(c.root, filepath.FromSlash(strings.TrimPrefix(cloudPath, "/")))
+	relative, err := filepath.Rel(c.root, localPath)
+	if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("cloud path illegal: %s", cloudPath)
+	}
+	return localPath, nil
+}
+
+func (c *LocalStorage) Put(localPath, cloudPath string, options ...PutOptions) (*string, error) {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Put: %s -> %s", localPath, cloudPath))
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return c.write(file, cloudPath)
+}
+
+func (c *LocalStorage) PutReader(reader io.Reader, cloudPath string, options ...PutOptions) (*string, error) {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Put: stream -> %s", cloudPath))
+	return c.write(reader, cloudPath)
+}
+
+// write 写入临时文件后替换, 避免留下不完整的对象
+func (c *LocalStorage) write(reader io.Reader, cloudPath string) (*string, error) {
+	target, err := c.localPath(cloudPath)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return nil, err
+	}
+	var temp = target + ".tmp"
+	file, err := os.Create(temp)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp, target)
+	}
+	if err != nil {
+		_ = os.Remove(temp)
+		return nil, err
+	}
+	return &cloudPath, nil
+}
+
+func (c *LocalStorage) Get(cloudPath, localPath string) error {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Get: %s -> %s", cloudPath, localPath))
+	reader, err := c.GetReader(cloudPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	file, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (c *LocalStorage) GetReader(cloudPath string) (io.ReadCloser, error) {
+	localPath, err := c.localPath(cloudPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, localError(cloudPath, err)
+	}
+	return file, nil
+}
+
+func (c *LocalStorage) List(prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.Walk(c.root, func(filePath string, fi os.FileInfo, errBack error) error {
+		if errBack != nil {
+			return errBack
+		}
+		if !fi.Mode().IsRegular() || strings.HasSuffix(fi.Name(), ".tmp") {
+			return nil
+		}
+		key, err := filepath.Rel(c.root, filePath)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(key)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, Size: fi.Size(), LastModified: fi.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (c *LocalStorage) Delete(cloudPath string) error {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Delete: %s", cloudPath))
+	localPath, err := c.localPath(cloudPath)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(localPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *LocalStorage) Stat(cloudPath string) (*Object, error) {
+	localPath, err := c.localPath(cloudPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, localError(cloudPath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s: %w", cloudPath, ErrNotExist)
+	}
+	return &Object{Key: strings.TrimPrefix(cloudPath, "/"), Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// localError 文件不存在时返回 ErrNotExist
+func localError(cloudPath string, err error) error {
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: %w", cloudPath, ErrNotExist)
+	}
+	return err
+}
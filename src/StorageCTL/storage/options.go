@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	// EncryptionManaged 服务端托管密钥加密 (COS: SSE-COS, S3: SSE-S3), 算法 AES256
+	EncryptionManaged = "aes256"
+	// EncryptionKMS KMS 密钥加密 (COS: SSE-KMS, S3: SSE-KMS), KMSKeyId 为空时使用默认密钥
+	EncryptionKMS = "kms"
+)
+
+// PutOptions 上传选项; 存储配置中的选项为默认值, 上传时传入的选项优先
+type PutOptions struct {
+	// StorageClass 存储类型; 如 COS: STANDARD, STANDARD_IA, ARCHIVE, DEEP_ARCHIVE, S3: STANDARD_IA, GLACIER
+	StorageClass string
+	// Encryption 服务端加密 (aes256|kms), 为空时不加密
+	Encryption string
+	// KMSKeyId KMS 密钥 ID
+	KMSKeyId string
+	// ContentType 对象类型, 为空时由存储识别
+	ContentType string
+	// Tags 对象标签 (如 tool, source, cluster)
+	Tags map[string]string
+}
+
+// ParsePutOptions 解析存储配置中的上传选项; 如 ?storage-class=ARCHIVE&sse=kms&kms-key=xxx&tag=env:prod
+func ParsePutOptions(query url.Values) (PutOptions, error) {
+	var options = PutOptions{
+		StorageClass: query.Get("storage-class"),
+		Encryption:   strings.ToLower(query.Get("sse")),
+		KMSKeyId:     query.Get("kms-key"),
+		ContentType:  query.Get("content-type"),
+	}
+	switch options.Encryption {
+	case "", EncryptionKMS:
+	case "aes256", "cos", "s3":
+		options.Encryption = EncryptionManaged
+	default:
+		return options, fmt.Errorf("CloudStorageConfig error: sse not supported: %s", options.Encryption)
+	}
+	if options.KMSKeyId != "" && options.Encryption != EncryptionKMS {
+		return options, fmt.Errorf("CloudStorageConfig error: kms-key requires sse=kms")
+	}
+	for _, value := range query["tag"] {
+		values := strings.SplitN(value, ":", 2)
+		if len(values) != 2 || values[0] == "" {
+			return options, fmt.Errorf("CloudStorageConfig error: tag format key:value: %s", value)
+		}
+		if options.Tags == nil {
+			options.Tags = map[string]string{}
+		}
+		options.Tags[values[0]] = values[1]
+	}
+	return options, nil
+}
+
+// merge 合并上传选项; values 中非空的选项覆盖默认值, 标签合并
+func (options PutOptions) merge(values ...PutOptions) PutOptions {
+	var tags = map[string]string{}
+	for key, value := range options.Tags {
+		tags[key] = value
+	}
+	for _, value := range values {
+		if value.StorageClass != "" {
+			options.StorageClass = value.StorageClass
+		}
+		if value.Encryption != "" {
+			options.Encryption = value.Encryption
+			options.KMSKeyId = value.KMSKeyId
+		}
+		if value.ContentType != "" {
+			options.ContentType = value.ContentType
+		}
+		for key, tag := range value.Tags {
+			tags[key] = tag
+		}
+	}
+	options.Tags = nil
+	if len(tags) > 0 {
+		options.Tags = tags
+	}
+	return options
+}
+
+// contentType 对象类型; 未配置时根据后缀识别备份归档
+func (options PutOptions) contentType(cloudPath string) string {
+	if options.ContentType != "" {
+		return options.ContentType
+	}
+	switch {
+	case strings.HasSuffix(cloudPath, ".age"):
+		return "application/octet-stream"
+	case strings.HasSuffix(cloudPath, ".zip"):
+		return "application/zip"
+	case strings.HasSuffix(cloudPath, ".tar.gz"), strings.HasSuffix(cloudPath, ".tgz"):
+		return "application/gzip"
+	case strings.HasSuffix(cloudPath, ".tar.zst"):
+		return "application/zstd"
+	}
+	return ""
+}
+
+// tagging 标签编码为 URL 查询格式 (k1=v1&k2=v2), 按 Key 排序
+func (options PutOptions) tagging() string {
+	var keys []string
+	for key := range options.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var values []string
+	for _, key := range keys {
+		values = append(values, url.QueryEscape(key)+"="+url.QueryEscape(options.Tags[key]))
+	}
+	return strings.Join(values, "&")
+}
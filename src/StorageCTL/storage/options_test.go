@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"hash/crc64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParsePutOptions(test *testing.T) {
+	query, _ := url.ParseQuery("storage-class=ARCHIVE&sse=KMS&kms-key=key-1&tag=env:prod&tag=team:ops")
+	options, err := ParsePutOptions(query)
+	if err != nil || options.StorageClass != "ARCHIVE" || options.Encryption != EncryptionKMS || options.KMSKeyId != "key-1" || options.Tags["team"] != "ops" {
+		test.Fatal(options, err)
+	}
+	for _, value := range []string{"sse=des", "kms-key=key-1", "tag=env"} {
+		query, _ = url.ParseQuery(value)
+		if _, err = ParsePutOptions(query); err == nil {
+			test.Fatal(value, "should fail")
+		}
+	}
+	var merged = options.merge(PutOptions{Encryption: EncryptionManaged, Tags: map[string]string{"tool": "nacos", "env": "test"}})
+	if merged.StorageClass != "ARCHIVE" || merged.Encryption != EncryptionManaged || merged.KMSKeyId != "" || len(merged.Tags) != 3 || merged.Tags["env"] != "test" {
+		test.Fatal(merged)
+	}
+	if options.Tags["env"] != "prod" {
+		test.Fatal("default options modified")
+	}
+	if value := merged.tagging(); value != "env=test&team=ops&tool=nacos" {
+		test.Fatal(value)
+	}
+}
+
+func TestTencentCosPutOptions(test *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		data, _ := io.ReadAll(r.Body)
+		w.Header().Set("x-cos-hash-crc64ecma", strconv.FormatUint(crc64.Checksum(data, crc64.MakeTable(crc64.ECMA)), 10))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	cloudStorage, err := NewCloudStorage("cos://id:key@" + strings.TrimPrefix(server.URL, "http://") + "?storage-class=ARCHIVE&sse=kms&kms-key=key-1")
+	if err != nil {
+		test.Fatal(err)
+	}
+	// 测试服务使用 http
+	cloudStorage.(*TencentCosClient).baseURL.BucketURL.Scheme = "http"
+	_, err = cloudStorage.PutReader(strings.NewReader("data"), "nacos/a.zip", PutOptions{Tags: map[string]string{"tool": "nacos", "source": "127.0.0.1:8848"}})
+	if err != nil {
+		test.Fatal(err)
+	}
+	var expected = map[string]string{
+		"X-Cos-Storage-Class":                         "ARCHIVE",
+		"X-Cos-Server-Side-Encryption":                "cos/kms",
+		"X-Cos-Server-Side-Encryption-Cos-Kms-Key-Id": "key-1",
+		"X-Cos-Tagging":                               "source=127.0.0.1%3A8848&tool=nacos",
+		"Content-Type":                                "application/zip",
+	}
+	for key, value := range expected {
+		if header.Get(key) != value {
+			test.Fatal(key, header.Get(key))
+		}
+	}
+}
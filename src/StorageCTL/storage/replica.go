@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/longyuan/lib.v3/scheduler"
+	"github.com/longyuan/lib.v3/secret"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Policy 多目标备份的成功策略
+type Policy string
+
+const (
+	// PolicyAll 所有目标上传成功
+	PolicyAll Policy = "all"
+	// PolicyAny 任一目标上传成功
+	PolicyAny Policy = "any"
+	// PolicyQuorum 超过半数目标上传成功
+	PolicyQuorum Policy = "quorum"
+)
+
+// ParsePolicy 解析成功策略 (all|any|quorum), 为空时为 all
+func ParsePolicy(value string) (Policy, error) {
+	switch policy := Policy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return PolicyAll, nil
+	case PolicyAll, PolicyAny, PolicyQuorum:
+		return policy, nil
+	}
+	return "", fmt.Errorf("replica policy not supported: %s", value)
+}
+
+// Satisfied succeeded 个目标成功时是否满足策略
+func (policy Policy) Satisfied(succeeded, total int) bool {
+	switch policy {
+	case PolicyAny:
+		return succeeded > 0
+	case PolicyQuorum:
+		return succeeded > total/2
+	}
+	return total > 0 && succeeded == total
+}
+
+// Target 备份目标
+type Target struct {
+	// Name 目标名称, 不含密钥; 如 cos(examplebucket-1250000000.cos.ap-guangzhou.myqcloud.com)
+	Name string
+	CloudStorage
+}
+
+// NewTargets 根据配置创建备份目标, 配置格式同 NewCloudStorage (支持密钥引用)
+func NewTargets(configs []string) ([]Target, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("CloudStorageConfig required")
+	}
+	var targets []Target
+	for _, config := range configs {
+		config, err := secret.Resolve(config)
+		if err != nil {
+			return nil, err
+		}
+		cloudStorage, err := NewCloudStorage(config)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, Target{Name: TargetName(config), CloudStorage: cloudStorage})
+	}
+	return targets, nil
+}
+
+// Names 目标名称
+func Names(targets []Target) []string {
+	var names []string
+	for _, target := range targets {
+		names = append(names, target.Name)
+	}
+	return names
+}
+
+// TargetName 存储配置的名称, 去掉密钥和查询参数
+func TargetName(config string) string {
+	u, err := url.Parse(config)
+	if err != nil || u.Scheme == "http" || u.Scheme == "https" {
+		// 旧格式: URL,SecretId,SecretKey
+		u, err = url.Parse(strings.Split(config, ",")[0])
+		if err != nil {
+			return "cos"
+		}
+		return "cos(" + u.Host + ")"
+	}
+	return strings.ToLower(u.Scheme) + "(" + strings.TrimSuffix(u.Host+u.Path, "/") + ")"
+}
+
+// TargetResult 单个目标的上传结果
+type TargetResult struct {
+	Target    string
+	CloudPath string
+	Err       error
+}
+
+// ReplicaResult 多目标上传结果
+type ReplicaResult struct {
+	Policy  Policy
+	Results []TargetResult
+}
+
+// Succeeded 上传成功的目标数量
+func (result *ReplicaResult) Succeeded() int {
+	var count int
+	for _, item := range result.Results {
+		if item.Err == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Uploaded 上传成功的目标; targets 为上传时的目标 (顺序与 Results 相同). 只在这些目标上清理过期备份
+func (result *ReplicaResult) Uploaded(targets []Target) []Target {
+	var uploaded []Target
+	for index, item := range result.Results {
+		if item.Err == nil && index < len(targets) {
+			uploaded = append(uploaded, targets[index])
+		}
+	}
+	return uploaded
+}
+
+// Degraded 满足策略但部分目标失败
+func (result *ReplicaResult) Degraded() bool {
+	var succeeded = result.Succeeded()
+	return succeeded < len(result.Results) && result.Policy.Satisfied(succeeded, len(result.Results))
+}
+
+// Err 不满足策略时返回异常, 包含每个失败目标的异常
+func (result *ReplicaResult) Err() error {
+	var succeeded = result.Succeeded()
+	if result.Policy.Satisfied(succeeded, len(result.Results)) {
+		return nil
+	}
+	var problems []string
+	for _, item := range result.Results {
+		if item.Err != nil {
+			problems = append(problems, item.Target+": "+item.Err.Error())
+		}
+	}
+	return fmt.Errorf("replicate failed (policy %s, %d/%d succeeded): %s", result.Policy, succeeded, len(result.Results), strings.Join(problems, "; "))
+}
+
+// String 每个目标的结果; 如 cos(a): nacos/x.zip, file(/data): 失败: xxx. 单个目标成功时为上传路径
+func (result *ReplicaResult) String() string {
+	if len(result.Results) == 1 && result.Results[0].Err == nil {
+		return result.Results[0].CloudPath
+	}
+	var values []string
+	for _, item := range result.Results {
+		if item.Err != nil {
+			values = append(values, item.Target+": 失败: "+item.Err.Error())
+			continue
+		}
+		values = append(values, item.Target+": "+item.CloudPath)
+	}
+	return strings.Join(values, ", ")
+}
+
+// replicaProgress 汇总所有目标的上传进度
+func replicaProgress(options UploadOptions, count int) func(index int) func(uploaded, total int64) {
+	var uploaded = make([]int64, count)
+	return func(index int) func(uploaded, total int64) {
+		if options.Progress == nil {
+			return nil
+		}
+		return func(value, total int64) {
+			atomic.StoreInt64(&uploaded[index], value)
+			var sum int64
+			for item := range uploaded {
+				sum += atomic.LoadInt64(&uploaded[item])
+			}
+			if total > 0 {
+				total *= int64(count)
+			}
+			options.Progress(sum, total)
+		}
+	}
+}
+
+// Replicate 并发上传本地文件到所有目标; 多个目标时每个目标使用单独的断点文件, options.Progress 为所有目标的进度之和, 成功上传的字节数累加到调度任务 (scheduler.AddUploaded)
+func Replicate(ctx context.Context, targets []Target, policy Policy, localPath, cloudPath string, options UploadOptions) *ReplicaResult {
+	var result = ReplicaResult{Policy: policy, Results: make([]TargetResult, len(targets))}
+	var progress = replicaProgress(options, len(targets))
+	var wait sync.WaitGroup
+	for index, target := range targets {
+		var targetOptions = options
+		targetOptions.Progress = progress(index)
+		if len(targets) > 1 {
+			if targetOptions.Checkpoint == "" {
+				targetOptions.Checkpoint = localPath + ".upload"
+			}
+			targetOptions.Checkpoint += fmt.Sprintf(".%d", index)
+		}
+		wait.Add(1)
+		go func(index int, target Target) {
+			defer wait.Done()
+			var item = TargetResult{Target: target.Name}
+			value, err := Upload(ctx, target, localPath, cloudPath, targetOptions)
+			if err != nil {
+				item.Err = err
+			} else {
+				item.CloudPath = *value
+			}
+			result.Results[index] = item
+		}(index, target)
+	}
+	wait.Wait()
+	if info, err := os.Stat(localPath); err == nil {
+		scheduler.AddUploaded(ctx, info.Size()*int64(result.Succeeded()))
+	}
+	return &result
+}
+
+// ReplicateReader 流式上传到所有目标; 读取一次 reader 并分发到每个目标, 失败的目标不影响其它目标
+func ReplicateReader(ctx context.Context, targets []Target, policy Policy, reader io.Reader, cloudPath string, options UploadOptions) *ReplicaResult {
+	var result = ReplicaResult{Policy: policy, Results: make([]TargetResult, len(targets))}
+	var progress = replicaProgress(options, len(targets))
+	var writers = make([]*io.PipeWriter, len(targets))
+	var wait sync.WaitGroup
+	for index, target := range targets {
+		var targetOptions = options
+		targetOptions.Progress = progress(index)
+		pipeReader, pipeWriter := io.Pipe()
+		writers[index] = pipeWriter
+		wait.Add(1)
+		go func(index int, target Target) {
+			defer wait.Done()
+			var item = TargetResult{Target: target.Name}
+			value, err := UploadReader(ctx, target, pipeReader, cloudPath, targetOptions)
+			if err != nil {
+				item.Err = err
+			} else {
+				item.CloudPath = *value
+				err = io.ErrClosedPipe
+			}
+			// 结束分发到该目标
+			_ = pipeReader.CloseWithError(err)
+			result.Results[index] = item
+		}(index, target)
+	}
+	var buffer = make([]byte, 32*1024)
+	var alive = len(writers)
+	var size int64
+	for alive > 0 {
+		n, err := reader.Read(buffer)
+		size += int64(n)
+		if n > 0 {
+			for index, writer := range writers {
+				if writer == nil {
+					continue
+				}
+				if _, writeErr := writer.Write(buffer[:n]); writeErr != nil {
+					writers[index] = nil
+					alive--
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			for _, writer := range writers {
+				if writer != nil {
+					_ = writer.CloseWithError(err)
+				}
+			}
+			wait.Wait()
+			return &result
+		}
+	}
+	for _, writer := range writers {
+		if writer != nil {
+			_ = writer.Close()
+		}
+	}
+	wait.Wait()
+	scheduler.AddUploaded(ctx, size*int64(result.Succeeded()))
+	return &result
+}
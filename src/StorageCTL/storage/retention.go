@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// backupNamePattern 备份文件名: <配置文件名>_<YYYY_MM_DD_HH_MM_SS><后缀>
+var backupNamePattern = regexp.MustCompile(`^(.+)_(\d{4}_\d{2}_\d{2}_\d{2}_\d{2}_\d{2})(\..*)?$`)
+
+// Retention 备份保留策略; 各规则保留的备份取并集, 所有规则为 0 时不清理
+type Retention struct {
+	// KeepLast 保留最近 N 个备份
+	KeepLast int
+	// KeepDaily 保留最近 N 天每天最新的备份
+	KeepDaily int
+	// KeepWeekly 保留最近 N 周每周最新的备份
+	KeepWeekly int
+	// KeepMonthly 保留最近 N 个月每月最新的备份
+	KeepMonthly int
+	// DryRun 只输出将要删除的备份, 不删除
+	DryRun bool
+}
+
+// Enabled 是否配置了保留规则
+func (retention Retention) Enabled() bool {
+	return retention.KeepLast > 0 || retention.KeepDaily > 0 || retention.KeepWeekly > 0 || retention.KeepMonthly > 0
+}
+
+func (retention Retention) String() string {
+	return fmt.Sprintf("last=%d daily=%d weekly=%d monthly=%d dry-run=%t",
+		retention.KeepLast, retention.KeepDaily, retention.KeepWeekly, retention.KeepMonthly, retention.DryRun)
+}
+
+// BackupName 解析备份文件名, 返回配置文件名和备份时间; 如 prod.yaml_2023_05_01_00_00_00.zip
+func BackupName(key string) (string, time.Time, bool) {
+	values := backupNamePattern.FindStringSubmatch(path.Base(key))
+	if values == nil {
+		return "", time.Time{}, false
+	}
+	backupTime, err := time.ParseInLocation("2006_01_02_15_04_05", values[2], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return values[1], backupTime, true
+}
+
+// Plan 计算需要删除的备份; 按配置文件名分组, 无法解析时间的对象始终保留, 每组至少保留最新的一个
+func (retention Retention) Plan(objects []Object) (keep []Object, prune []Object) {
+	if !retention.Enabled() {
+		return objects, nil
+	}
+	type backup struct {
+		object Object
+		time   time.Time
+	}
+	var groups = map[string][]backup{}
+	for _, object := range objects {
+		name, backupTime, ok := BackupName(object.Key)
+		if !ok {
+			keep = append(keep, object)
+			continue
+		}
+		groups[name] = append(groups[name], backup{object: object, time: backupTime})
+	}
+	for _, backups := range groups {
+		// 最新的在前
+		sort.Slice(backups, func(i, j int) bool {
+			return backups[i].time.After(backups[j].time)
+		})
+		var kept = make([]bool, len(backups))
+		kept[0] = true
+		for index := 0; index < len(backups) && index < retention.KeepLast; index++ {
+			kept[index] = true
+		}
+		var rules = []struct {
+			count  int
+			period func(value time.Time) string
+		}{
+			{retention.KeepDaily, func(value time.Time) string { return value.Format("2006-01-02") }},
+			{retention.KeepWeekly, func(value time.Time) string {
+				year, week := value.ISOWeek()
+				return strconv.Itoa(year) + "-" + strconv.Itoa(week)
+			}},
+			{retention.KeepMonthly, func(value time.Time) string { return value.Format("2006-01") }},
+		}
+		for _, rule := range rules {
+			var periods = map[string]bool{}
+			for index, item := range backups {
+				if len(periods) >= rule.count {
+					break
+				}
+				var period = rule.period(item.time)
+				if periods[period] {
+					continue
+				}
+				periods[period] = true
+				kept[index] = true
+			}
+		}
+		for index, item := range backups {
+			if kept[index] {
+				keep = append(keep, item.object)
+			} else {
+				prune = append(prune, item.object)
+			}
+		}
+	}
+	sortObjects(keep)
+	sortObjects(prune)
+	return keep, prune
+}
+
+// Prune 按保留策略清理 prefix 下的备份; name 配置文件名, 为空时清理所有配置的备份. 返回删除 (dry-run 时为将要删除) 的备份
+func Prune(cloudStorage CloudStorage, prefix, name string, retention Retention) ([]Object, error) {
+	if !retention.Enabled() {
+		return nil, nil
+	}
+	objects, err := cloudStorage.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		var filtered []Object
+		for _, object := range objects {
+			if backupName, _, ok := BackupName(object.Key); ok && backupName == name {
+				filtered = append(filtered, object)
+			}
+		}
+		objects = filtered
+	}
+	keep, prune := retention.Plan(objects)
+	var tag = "[Retention]"
+	if retention.DryRun {
+		tag = "[Retention] (dry-run)"
+	}
+	logger.Info(fmt.Sprintf("%s %s%s: keep %d, prune %d (%s)", tag, prefix, name, len(keep), len(prune), retention))
+	for index, object := range prune {
+		logger.Warn(fmt.Sprintf("%s Prune: %s (%s)", tag, object.Key, object.LastModified.Format("2006-01-02 15:04:05")))
+		if retention.DryRun {
+			continue
+		}
+		err = cloudStorage.Delete(object.Key)
+		if err != nil {
+			return prune[:index], err
+		}
+	}
+	return prune, nil
+}
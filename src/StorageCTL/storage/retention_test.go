@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionPlan(test *testing.T) {
+	var objects []Object
+	// 2023-01-01 ~ 2023-03-31 每天两个备份
+	var start = time.Date(2023, 1, 1, 1, 0, 0, 0, time.Local)
+	for day := 0; day < 90; day++ {
+		for _, hour := range []int{0, 12} {
+			var backupTime = start.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+			objects = append(objects, Object{
+				Key: "nacos/" + backupTime.Format("2006_01_02") + "/prod.yaml_" + backupTime.Format("2006_01_02_15_04_05") + ".tar.gz.age",
+			})
+		}
+	}
+	objects = append(objects, Object{Key: "nacos/readme.txt"})
+
+	keep, prune := Retention{}.Plan(objects)
+	if len(keep) != len(objects) || len(prune) != 0 {
+		test.Fatal("disabled retention should keep all")
+	}
+
+	keep, prune = Retention{KeepLast: 3}.Plan(objects)
+	if len(keep) != 4 || len(prune) != 177 {
+		test.Fatal(len(keep), len(prune))
+	}
+
+	keep, _ = Retention{KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 12}.Plan(objects)
+	var keys []string
+	for _, object := range keep {
+		keys = append(keys, object.Key)
+	}
+	// 7 天 (03-25 ~ 03-31) + 4 周中的 03-19、03-12 + 1、2 月最后一个 + readme
+	var joined = strings.Join(keys, ",")
+	for _, key := range []string{"prod.yaml_2023_03_31_13_00_00", "prod.yaml_2023_03_25_13_00_00", "prod.yaml_2023_03_12_13_00_00",
+		"prod.yaml_2023_01_31_13_00_00", "prod.yaml_2023_02_28_13_00_00", "readme.txt"} {
+		if !strings.Contains(joined, key) {
+			test.Fatal(key, joined)
+		}
+	}
+	if strings.Contains(joined, "prod.yaml_2023_03_31_01_00_00") || len(keep) != 7+2+2+1 {
+		test.Fatal(len(keep), joined)
+	}
+}
+
+func TestPrune(test *testing.T) {
+	cloudStorage, err := NewLocal(test.TempDir())
+	if err != nil {
+		test.Fatal(err)
+	}
+	for _, key := range []string{"gitlab/2023_05_01/a.yaml_2023_05_01_00_00_00.zip", "gitlab/2023_05_02/a.yaml_2023_05_02_00_00_00.zip",
+		"gitlab/2023_05_02/b.yaml_2023_05_02_00_00_00.zip"} {
+		_, err = cloudStorage.PutReader(strings.NewReader(key), key)
+		if err != nil {
+			test.Fatal(err)
+		}
+	}
+	pruned, err := Prune(cloudStorage, "gitlab/", "a.yaml", Retention{KeepLast: 1, DryRun: true})
+	if err != nil || len(pruned) != 1 {
+		test.Fatal(pruned, err)
+	}
+	objects, _ := cloudStorage.List("gitlab/")
+	if len(objects) != 3 {
+		test.Fatal("dry-run should not delete")
+	}
+	pruned, err = Prune(cloudStorage, "gitlab/", "", Retention{KeepLast: 1})
+	if err != nil || len(pruned) != 1 || pruned[0].Key != "gitlab/2023_05_01/a.yaml_2023_05_01_00_00_00.zip" {
+		test.Fatal(pruned, err)
+	}
+	objects, _ = cloudStorage.List("gitlab/")
+	if len(objects) != 2 {
+		test.Fatal(objects)
+	}
+}
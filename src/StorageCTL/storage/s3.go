@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"io"
+	"strings"
+)
+
+func (c *S3Client) Put(localPath, cloudPath string, options ...PutOptions) (*string, error) {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Put: %s -> %s", localPath, cloudPath))
+	putOptions, err := c.putOptions(cloudPath, options...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.client.FPutObject(context.Background(), c.bucket, cloudPath, localPath, putOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudPath, nil
+}
+
+func (c *S3Client) PutReader(reader io.Reader, cloudPath string, options ...PutOptions) (*string, error) {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Put: stream -> %s", cloudPath))
+	putOptions, err := c.putOptions(cloudPath, options...)
+	if err != nil {
+		return nil, err
+	}
+	// 大小未知时按分片上传; 未指定分片大小时 minio 按 5TB 计算分片 (每个分片缓存约 576MB), 固定为 16MB (最大 156GB)
+	putOptions.PartSize = defaultPartSize
+	_, err = c.client.PutObject(context.Background(), c.bucket, cloudPath, reader, -1, putOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudPath, nil
+}
+
+func (c *S3Client) Get(cloudPath, localPath string) error {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Get: %s -> %s", cloudPath, localPath))
+	err := c.client.FGetObject(context.Background(), c.bucket, cloudPath, localPath, minio.GetObjectOptions{})
+	return s3Error(cloudPath, err)
+}
+
+func (c *S3Client) GetReader(cloudPath string) (io.ReadCloser, error) {
+	// GetObject 在首次读取时才返回异常, 先确认对象存在
+	_, err := c.Stat(cloudPath)
+	if err != nil {
+		return nil, err
+	}
+	object, err := c.client.GetObject(context.Background(), c.bucket, cloudPath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(cloudPath, err)
+	}
+	return object, nil
+}
+
+func (c *S3Client) List(prefix string) ([]Object, error) {
+	var objects []Object
+	for item := range c.client.ListObjects(context.Background(), c.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if item.Err != nil {
+			return nil, item.Err
+		}
+		objects = append(objects, Object{Key: item.Key, Size: item.Size, LastModified: item.LastModified, ETag: strings.Trim(item.ETag, "\"")})
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (c *S3Client) Delete(cloudPath string) error {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Delete: %s", cloudPath))
+	return c.client.RemoveObject(context.Background(), c.bucket, cloudPath, minio.RemoveObjectOptions{})
+}
+
+func (c *S3Client) Stat(cloudPath string) (*Object, error) {
+	info, err := c.client.StatObject(context.Background(), c.bucket, cloudPath, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error(cloudPath, err)
+	}
+	return &Object{Key: info.Key, Size: info.Size, LastModified: info.LastModified, ETag: strings.Trim(info.ETag, "\"")}, nil
+}
+
+// s3Error 对象不存在时返回 ErrNotExist
+func s3Error(cloudPath string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if code := minio.ToErrorResponse(err).Code; code == "NoSuchKey" || code == "NotFound" {
+		return fmt.Errorf("%s: %w", cloudPath, ErrNotExist)
+	}
+	return err
+}
+
+func (c *S3Client) initiateUpload(ctx context.Context, cloudPath string, options PutOptions) (string, error) {
+	putOptions, err := c.putOptions(cloudPath, options)
+	if err != nil {
+		return "", err
+	}
+	return (&minio.Core{Client: c.client}).NewMultipartUpload(ctx, c.bucket, cloudPath, putOptions)
+}
+
+func (c *S3Client) uploadPart(ctx context.Context, cloudPath, uploadId string, number int, reader io.Reader, size int64) (string, error) {
+	part, err := (&minio.Core{Client: c.client}).PutObjectPart(ctx, c.bucket, cloudPath, uploadId, number, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", err
+	}
+	return part.ETag, nil
+}
+
+func (c *S3Client) completeUpload(ctx context.Context, cloudPath, uploadId string, parts []Part) error {
+	var completeParts []minio.CompletePart
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.Number, ETag: part.ETag})
+	}
+	_, err := (&minio.Core{Client: c.client}).CompleteMultipartUpload(ctx, c.bucket, cloudPath, uploadId, completeParts, minio.PutObjectOptions{})
+	return err
+}
+
+func (c *S3Client) listParts(ctx context.Context, cloudPath, uploadId string) ([]Part, error) {
+	var parts []Part
+	var marker int
+	for {
+		result, err := (&minio.Core{Client: c.client}).ListObjectParts(ctx, c.bucket, cloudPath, uploadId, marker, 1000)
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchUpload" {
+				return nil, fmt.Errorf("%s: %w", uploadId, errNoSuchUpload)
+			}
+			return nil, err
+		}
+		for _, part := range result.ObjectParts {
+			parts = append(parts, Part{Number: part.PartNumber, ETag: part.ETag, Size: part.Size})
+		}
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+func (c *S3Client) abortUpload(ctx context.Context, cloudPath, uploadId string) error {
+	return (&minio.Core{Client: c.client}).AbortMultipartUpload(ctx, c.bucket, cloudPath, uploadId)
+}
+
+// putOptions 上传选项; 合并默认上传选项
+func (c *S3Client) putOptions(cloudPath string, values ...PutOptions) (minio.PutObjectOptions, error) {
+	var options = c.options.merge(values...)
+	var putOptions = minio.PutObjectOptions{ContentType: options.contentType(cloudPath), StorageClass: options.StorageClass, UserTags: options.Tags}
+	switch options.Encryption {
+	case EncryptionManaged:
+		putOptions.ServerSideEncryption = encrypt.NewSSE()
+	case EncryptionKMS:
+		sse, err := encrypt.NewSSEKMS(options.KMSKeyId, nil)
+		if err != nil {
+			return putOptions, err
+		}
+		putOptions.ServerSideEncryption = sse
+	}
+	return putOptions, nil
+}
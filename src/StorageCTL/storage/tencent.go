@@ -3,14 +3,165 @@ package storage
 import (
 	"context"
 	"fmt"
-	"github.com/fatih/color"
+	"github.com/longyuan/lib.v3/logger"
+	"github.com/tencentyun/cos-go-sdk-v5"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func (c *TencentCosClient) Put(localPath, cloudPath string) (*string, error) {
-	color.Blue(fmt.Sprintf("[Cloud Storage] Put: %s -> %s", localPath, cloudPath))
-	_, _, err := c.client.Object.Upload(context.Background(), cloudPath, localPath, nil)
+func (c *TencentCosClient) Put(localPath, cloudPath string, options ...PutOptions) (*string, error) {
+	return Upload(context.Background(), c, localPath, cloudPath, UploadOptions{Put: PutOptions{}.merge(options...)})
+}
+
+func (c *TencentCosClient) PutReader(reader io.Reader, cloudPath string, options ...PutOptions) (*string, error) {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Put: stream -> %s", cloudPath))
+	_, err := c.client.Object.Put(context.Background(), cloudPath, reader, &cos.ObjectPutOptions{ObjectPutHeaderOptions: c.header(cloudPath, options...)})
 	if err != nil {
 		return nil, err
 	}
-	return &cloudPath, err
+	return &cloudPath, nil
+}
+
+func (c *TencentCosClient) Get(cloudPath, localPath string) error {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Get: %s -> %s", cloudPath, localPath))
+	_, err := c.client.Object.GetToFile(context.Background(), cloudPath, localPath, nil)
+	return cosError(cloudPath, err)
+}
+
+func (c *TencentCosClient) GetReader(cloudPath string) (io.ReadCloser, error) {
+	response, err := c.client.Object.Get(context.Background(), cloudPath, nil)
+	if err != nil {
+		return nil, cosError(cloudPath, err)
+	}
+	return response.Body, nil
+}
+
+func (c *TencentCosClient) List(prefix string) ([]Object, error) {
+	var objects []Object
+	var marker string
+	for {
+		result, _, err := c.client.Bucket.Get(context.Background(), &cos.BucketGetOptions{
+			Prefix: prefix, Marker: marker, MaxKeys: 1000,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Contents {
+			lastModified, _ := time.Parse(time.RFC3339, item.LastModified)
+			objects = append(objects, Object{
+				Key: item.Key, Size: item.Size, LastModified: lastModified, ETag: strings.Trim(item.ETag, "\""),
+			})
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextMarker
+		if marker == "" && len(result.Contents) > 0 {
+			marker = result.Contents[len(result.Contents)-1].Key
+		}
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (c *TencentCosClient) Delete(cloudPath string) error {
+	logger.Info(fmt.Sprintf("[Cloud Storage] Delete: %s", cloudPath))
+	_, err := c.client.Object.Delete(context.Background(), cloudPath)
+	return err
+}
+
+func (c *TencentCosClient) Stat(cloudPath string) (*Object, error) {
+	response, err := c.client.Object.Head(context.Background(), cloudPath, nil)
+	if err != nil {
+		return nil, cosError(cloudPath, err)
+	}
+	var object = Object{Key: cloudPath, ETag: strings.Trim(response.Header.Get("ETag"), "\"")}
+	object.Size, _ = strconv.ParseInt(response.Header.Get("Content-Length"), 10, 64)
+	object.LastModified, _ = http.ParseTime(response.Header.Get("Last-Modified"))
+	return &object, nil
+}
+
+// cosError 对象不存在时返回 ErrNotExist
+func cosError(cloudPath string, err error) error {
+	if err != nil && cos.IsNotFoundError(err) {
+		return fmt.Errorf("%s: %w", cloudPath, ErrNotExist)
+	}
+	return err
+}
+
+func (c *TencentCosClient) initiateUpload(ctx context.Context, cloudPath string, options PutOptions) (string, error) {
+	result, _, err := c.client.Object.InitiateMultipartUpload(ctx, cloudPath, &cos.InitiateMultipartUploadOptions{ObjectPutHeaderOptions: c.header(cloudPath, options)})
+	if err != nil {
+		return "", err
+	}
+	return result.UploadID, nil
+}
+
+func (c *TencentCosClient) uploadPart(ctx context.Context, cloudPath, uploadId string, number int, reader io.Reader, size int64) (string, error) {
+	response, err := c.client.Object.UploadPart(ctx, cloudPath, uploadId, number, reader, &cos.ObjectUploadPartOptions{ContentLength: size})
+	if err != nil {
+		return "", err
+	}
+	return response.Header.Get("ETag"), nil
+}
+
+func (c *TencentCosClient) completeUpload(ctx context.Context, cloudPath, uploadId string, parts []Part) error {
+	var options = &cos.CompleteMultipartUploadOptions{}
+	for _, part := range parts {
+		options.Parts = append(options.Parts, cos.Object{PartNumber: part.Number, ETag: part.ETag})
+	}
+	_, _, err := c.client.Object.CompleteMultipartUpload(ctx, cloudPath, uploadId, options)
+	return err
+}
+
+func (c *TencentCosClient) listParts(ctx context.Context, cloudPath, uploadId string) ([]Part, error) {
+	var parts []Part
+	var marker string
+	for {
+		result, _, err := c.client.Object.ListParts(ctx, cloudPath, uploadId, &cos.ObjectListPartsOptions{MaxParts: "1000", PartNumberMarker: marker})
+		if err != nil {
+			if cosErr, ok := cos.IsCOSError(err); ok && (cosErr.Code == "NoSuchUpload" || cos.IsNotFoundError(err)) {
+				return nil, fmt.Errorf("%s: %w", uploadId, errNoSuchUpload)
+			}
+			return nil, err
+		}
+		for _, part := range result.Parts {
+			parts = append(parts, Part{Number: part.PartNumber, ETag: part.ETag, Size: part.Size})
+		}
+		if !result.IsTruncated || result.NextPartNumberMarker == "" {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+func (c *TencentCosClient) abortUpload(ctx context.Context, cloudPath, uploadId string) error {
+	_, err := c.client.Object.AbortMultipartUpload(ctx, cloudPath, uploadId)
+	return err
+}
+
+// header 上传请求头; 合并默认上传选项
+func (c *TencentCosClient) header(cloudPath string, values ...PutOptions) *cos.ObjectPutHeaderOptions {
+	var options = c.options.merge(values...)
+	var header = &cos.ObjectPutHeaderOptions{ContentType: options.contentType(cloudPath), XCosStorageClass: options.StorageClass}
+	var extra = http.Header{}
+	switch options.Encryption {
+	case EncryptionManaged:
+		header.XCosServerSideEncryption = "AES256"
+	case EncryptionKMS:
+		extra.Set("x-cos-server-side-encryption", "cos/kms")
+		if options.KMSKeyId != "" {
+			extra.Set("x-cos-server-side-encryption-cos-kms-key-id", options.KMSKeyId)
+		}
+	}
+	if len(options.Tags) > 0 {
+		extra.Set("x-cos-tagging", options.tagging())
+	}
+	if len(extra) > 0 {
+		header.XOptionHeader = &extra
+	}
+	return header
 }
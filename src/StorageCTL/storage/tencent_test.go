@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTencentCosClient(test *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/":
+			// 分页: 第一页返回 a, 第二页返回 b
+			var key, truncated = "nacos/a.zip", "true"
+			if r.URL.Query().Get("marker") != "" {
+				key, truncated = "nacos/b.zip", "false"
+			}
+			_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>%s</IsTruncated><NextMarker>%s</NextMarker>`+
+				`<Contents><Key>%s</Key><Size>10</Size><ETag>"etag"</ETag><LastModified>2023-05-01T00:00:00.000Z</LastModified></Contents>`+
+				`</ListBucketResult>`, truncated, key, key)
+		case r.Method == "HEAD" && r.URL.Path == "/nacos/a.zip":
+			w.Header().Set("Content-Length", "10")
+			w.Header().Set("Last-Modified", "Mon, 01 May 2023 00:00:00 GMT")
+			w.WriteHeader(http.StatusOK)
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+		}
+	}))
+	defer server.Close()
+
+	cloudStorage, err := NewTencentCOS(server.URL, "id", "key")
+	if err != nil {
+		test.Fatal(err)
+	}
+	objects, err := cloudStorage.List("nacos/")
+	if err != nil || len(objects) != 2 || objects[1].Key != "nacos/b.zip" || objects[0].ETag != "etag" || objects[0].LastModified.Year() != 2023 {
+		test.Fatal(objects, err)
+	}
+	object, err := cloudStorage.Stat("nacos/a.zip")
+	if err != nil || object.Size != 10 || object.LastModified.Month() != 5 {
+		test.Fatal(object, err)
+	}
+	if _, err = cloudStorage.Stat("nacos/c.zip"); !errors.Is(err, ErrNotExist) {
+		test.Fatal(err)
+	}
+	if _, err = cloudStorage.GetReader("nacos/c.zip"); !errors.Is(err, ErrNotExist) {
+		test.Fatal(err)
+	}
+	if err = cloudStorage.Delete("nacos/a.zip"); err != nil {
+		test.Fatal(err)
+	}
+}
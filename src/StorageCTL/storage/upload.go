@@ -0,0 +1,570 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/longyuan/lib.v3/logger"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// UploadOptions 上传配置
+type UploadOptions struct {
+	// PartSize 分片大小, 默认 16MB; 小于分片大小的文件直接上传
+	PartSize int64
+	// Concurrency 并发上传的分片数量, 默认 4
+	Concurrency int
+	// BandwidthLimit 带宽限制 (字节/秒), 0 不限制
+	BandwidthLimit int64
+	// Checkpoint 断点文件, 默认 <本地文件>.upload; 上传失败时保留, 再次上传同一文件 (路径、大小、修改时间不变) 时跳过已上传的分片.
+	// 定时备份每次生成新的归档 (文件名含时间), 不会续传, 失败的分片只在本次上传中重试; 续传用于 sctl 等重复上传同一文件的场景
+	Checkpoint string
+	// Progress 上传进度回调; total 未知时为 -1
+	Progress func(uploaded, total int64)
+	// Put 上传选项 (存储类型、服务端加密、标签)
+	Put PutOptions
+}
+
+const (
+	defaultPartSize    = 16 << 20
+	defaultConcurrency = 4
+	partAttempts       = 3
+	// maxParts 分片数量上限 (COS、S3)
+	maxParts = 10000
+)
+
+// errNoSuchUpload 分片上传不存在 (已完成、已取消或过期)
+var errNoSuchUpload = errors.New("no such upload")
+
+func (options UploadOptions) withDefaults(localPath string) UploadOptions {
+	if options.PartSize <= 0 {
+		options.PartSize = defaultPartSize
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+	if options.Checkpoint == "" && localPath != "" {
+		options.Checkpoint = localPath + ".upload"
+	}
+	return options
+}
+
+// filePartSize 文件的分片大小; 分片数量超过 maxParts 时增大分片大小 (size/maxParts 向上取整)
+func filePartSize(size, partSize int64) int64 {
+	if minimum := (size + maxParts - 1) / maxParts; partSize < minimum {
+		return minimum
+	}
+	return partSize
+}
+
+// Part 已上传的分片
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// multipartUploader 分片上传, 由支持分片的存储实现
+type multipartUploader interface {
+	initiateUpload(ctx context.Context, cloudPath string, options PutOptions) (string, error)
+	uploadPart(ctx context.Context, cloudPath, uploadId string, number int, reader io.Reader, size int64) (string, error)
+	completeUpload(ctx context.Context, cloudPath, uploadId string, parts []Part) error
+	// listParts 已上传的分片; 上传不存在时返回 errNoSuchUpload
+	listParts(ctx context.Context, cloudPath, uploadId string) ([]Part, error)
+	abortUpload(ctx context.Context, cloudPath, uploadId string) error
+}
+
+// checkpoint 断点信息; 本地文件大小、修改时间、目标路径和分片大小一致时才续传
+type checkpoint struct {
+	CloudPath string    `json:"cloudPath"`
+	UploadId  string    `json:"uploadId"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	PartSize  int64     `json:"partSize"`
+	Parts     []Part    `json:"parts"`
+}
+
+func loadCheckpoint(checkpointPath string) *checkpoint {
+	data, err := os.ReadFile(checkpointPath)
+	if err != nil {
+		return nil
+	}
+	var value checkpoint
+	if json.Unmarshal(data, &value) != nil {
+		return nil
+	}
+	return &value
+}
+
+func (value *checkpoint) save(checkpointPath string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var temp = checkpointPath + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, checkpointPath)
+}
+
+// Upload 上传本地文件; 支持分片的存储 (COS、S3) 按分片并发上传, 失败的分片自动重试, 中断后可从断点文件续传
+func Upload(ctx context.Context, cloudStorage CloudStorage, localPath, cloudPath string, options UploadOptions) (*string, error) {
+	options = options.withDefaults(localPath)
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var meter = newMeter(options.BandwidthLimit, info.Size(), options.Progress)
+	options.PartSize = filePartSize(info.Size(), options.PartSize)
+
+	multipart, ok := cloudStorage.(multipartUploader)
+	if !ok || info.Size() <= options.PartSize {
+		logger.Info(fmt.Sprintf("[Cloud Storage] Upload: %s -> %s (%s)", localPath, cloudPath, SizeFormat(info.Size())))
+		return cloudStorage.PutReader(meter.reader(ctx, file, nil), cloudPath, options.Put)
+	}
+
+	// 读取断点
+	var state = loadCheckpoint(options.Checkpoint)
+	if state != nil && (state.CloudPath != cloudPath || state.Size != info.Size() ||
+		!state.ModTime.Equal(info.ModTime()) || state.PartSize != options.PartSize) {
+		state = nil
+	}
+	if state != nil {
+		state, err = verifyCheckpoint(ctx, multipart, state)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if state == nil {
+		uploadId, err := multipart.initiateUpload(ctx, cloudPath, options.Put)
+		if err != nil {
+			return nil, err
+		}
+		state = &checkpoint{CloudPath: cloudPath, UploadId: uploadId, Size: info.Size(), ModTime: info.ModTime(), PartSize: options.PartSize}
+		err = state.save(options.Checkpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var partCount = int((info.Size() + options.PartSize - 1) / options.PartSize)
+	var done = map[int]bool{}
+	for _, part := range state.Parts {
+		done[part.Number] = true
+		meter.add(part.Size)
+	}
+	logger.Info(fmt.Sprintf("[Cloud Storage] Upload: %s -> %s (%s, %d/%d parts)",
+		localPath, cloudPath, SizeFormat(info.Size()), len(done), partCount))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var lock sync.Mutex
+	var firstErr error
+	var numbers = make(chan int)
+	var wait sync.WaitGroup
+	for worker := 0; worker < options.Concurrency; worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for number := range numbers {
+				var offset = int64(number-1) * options.PartSize
+				var size = options.PartSize
+				if offset+size > info.Size() {
+					size = info.Size() - offset
+				}
+				etag, err := uploadPart(ctx, multipart, meter, file, state.CloudPath, state.UploadId, number, offset, size)
+				lock.Lock()
+				if err == nil {
+					state.Parts = append(state.Parts, Part{Number: number, ETag: etag, Size: size})
+					err = state.save(options.Checkpoint)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for number := 1; number <= partCount; number++ {
+		if done[number] {
+			continue
+		}
+		select {
+		case numbers <- number:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(numbers)
+	wait.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, fmt.Errorf("upload %s interrupted (checkpoint %s): %w", cloudPath, options.Checkpoint, firstErr)
+	}
+
+	sort.Slice(state.Parts, func(i, j int) bool {
+		return state.Parts[i].Number < state.Parts[j].Number
+	})
+	err = multipart.completeUpload(ctx, cloudPath, state.UploadId, state.Parts)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Remove(options.Checkpoint)
+	return &cloudPath, nil
+}
+
+// verifyCheckpoint 确认断点的分片上传仍然存在, 只保留服务端存在且 ETag 一致的分片; 上传不存在 (过期或已取消) 时返回 nil, 重新开始上传
+func verifyCheckpoint(ctx context.Context, multipart multipartUploader, state *checkpoint) (*checkpoint, error) {
+	parts, err := multipart.listParts(ctx, state.CloudPath, state.UploadId)
+	if errors.Is(err, errNoSuchUpload) {
+		logger.Warn(fmt.Sprintf("[Cloud Storage] Upload %s: checkpoint upload %s not found, restart", state.CloudPath, state.UploadId))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var uploaded = map[int]string{}
+	for _, part := range parts {
+		uploaded[part.Number] = strings.Trim(part.ETag, "\"")
+	}
+	var kept []Part
+	for _, part := range state.Parts {
+		if etag, ok := uploaded[part.Number]; ok && etag == strings.Trim(part.ETag, "\"") {
+			kept = append(kept, part)
+		}
+	}
+	state.Parts = kept
+	return state, nil
+}
+
+// uploadPart 上传单个分片 (reader 中 offset 开始的 size 字节), 失败时指数退避重试
+func uploadPart(ctx context.Context, multipart multipartUploader, meter *meter, readerAt io.ReaderAt, cloudPath, uploadId string, number int, offset, size int64) (string, error) {
+	var err error
+	for attempt := 0; attempt < partAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Second << (attempt - 1)):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		var sent int64
+		var reader = meter.reader(ctx, io.NewSectionReader(readerAt, offset, size), &sent)
+		var etag string
+		etag, err = multipart.uploadPart(ctx, cloudPath, uploadId, number, reader, size)
+		if err == nil {
+			return etag, nil
+		}
+		// 失败的分片重新计算进度
+		meter.add(-atomic.LoadInt64(&sent))
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		logger.Warn(fmt.Sprintf("[Cloud Storage] Upload part %d failed (%d/%d): %s", number, attempt+1, partAttempts, err))
+	}
+	return "", err
+}
+
+// UploadReader 流式上传, 支持带宽限制和进度; 支持分片的存储 (COS、S3) 按分片并发上传, 失败的分片自动重试.
+// 内存中最多缓存 Concurrency+1 个分片, 流的大小上限为 PartSize*10000 (默认 156GB); 流无法续传, 失败时取消分片上传
+func UploadReader(ctx context.Context, cloudStorage CloudStorage, reader io.Reader, cloudPath string, options UploadOptions) (*string, error) {
+	options = options.withDefaults("")
+	var meter = newMeter(options.BandwidthLimit, -1, options.Progress)
+	multipart, ok := cloudStorage.(multipartUploader)
+	if !ok {
+		return cloudStorage.PutReader(meter.reader(ctx, reader, nil), cloudPath, options.Put)
+	}
+	// 读取第一个分片, 小于分片大小时直接上传
+	var first = make([]byte, options.PartSize)
+	n, err := io.ReadFull(reader, first)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return cloudStorage.PutReader(meter.reader(ctx, bytes.NewReader(first[:n]), nil), cloudPath, options.Put)
+	}
+	if err != nil {
+		return nil, err
+	}
+	uploadId, err := multipart.initiateUpload(ctx, cloudPath, options.Put)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("[Cloud Storage] Upload: stream -> %s (part %s, concurrency %d)", cloudPath, SizeFormat(options.PartSize), options.Concurrency))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type job struct {
+		number int
+		data   []byte
+	}
+	var lock sync.Mutex
+	var firstErr error
+	var parts []Part
+	var fail = func(err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	// 分片缓存, 最多 Concurrency+1 个 (上传中的分片和正在读取的分片)
+	var free = make(chan []byte, options.Concurrency+1)
+	var allocated = 1
+	var buffer = func() ([]byte, error) {
+		select {
+		case data := <-free:
+			return data, nil
+		default:
+		}
+		if allocated <= options.Concurrency {
+			allocated++
+			return make([]byte, options.PartSize), nil
+		}
+		select {
+		case data := <-free:
+			return data, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	var jobs = make(chan job)
+	var wait sync.WaitGroup
+	for worker := 0; worker < options.Concurrency; worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for item := range jobs {
+				var size = int64(len(item.data))
+				etag, err := uploadPart(ctx, multipart, meter, bytes.NewReader(item.data), cloudPath, uploadId, item.number, 0, size)
+				if err != nil {
+					fail(err)
+				} else {
+					lock.Lock()
+					parts = append(parts, Part{Number: item.number, ETag: etag, Size: size})
+					lock.Unlock()
+				}
+				free <- item.data[:cap(item.data)]
+			}
+		}()
+	}
+	var next = job{number: 1, data: first}
+	for {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil || len(next.data) < int(options.PartSize) {
+			break
+		}
+		data, err := buffer()
+		if err != nil {
+			break
+		}
+		n, err := io.ReadFull(reader, data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			fail(err)
+			break
+		}
+		if next.number >= maxParts {
+			fail(fmt.Errorf("stream exceeds %d parts of %s", maxParts, SizeFormat(options.PartSize)))
+			break
+		}
+		next = job{number: next.number + 1, data: data[:n]}
+	}
+	close(jobs)
+	wait.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		// 取消分片上传, 清理已上传的分片 (ctx 可能已取消)
+		if err := multipart.abortUpload(context.Background(), cloudPath, uploadId); err != nil {
+			logger.Warn(fmt.Sprintf("[Cloud Storage] Abort upload %s: %s", cloudPath, err))
+		}
+		return nil, fmt.Errorf("upload %s: %w", cloudPath, firstErr)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+	err = multipart.completeUpload(ctx, cloudPath, uploadId, parts)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudPath, nil
+}
+
+// meter 带宽限制和进度统计, 所有分片共享
+type meter struct {
+	limit    int64
+	total    int64
+	uploaded int64
+	progress func(uploaded, total int64)
+
+	lock sync.Mutex
+	next time.Time
+}
+
+func newMeter(limit, total int64, progress func(uploaded, total int64)) *meter {
+	return &meter{limit: limit, total: total, progress: progress}
+}
+
+func (meter *meter) add(size int64) {
+	var uploaded = atomic.AddInt64(&meter.uploaded, size)
+	if meter.progress != nil {
+		meter.progress(uploaded, meter.total)
+	}
+}
+
+// wait 按带宽限制等待 size 字节的发送时间
+func (meter *meter) wait(ctx context.Context, size int) error {
+	if meter.limit <= 0 {
+		return nil
+	}
+	meter.lock.Lock()
+	var now = time.Now()
+	if meter.next.Before(now) {
+		meter.next = now
+	}
+	var delay = meter.next.Sub(now)
+	meter.next = meter.next.Add(time.Duration(float64(size) / float64(meter.limit) * float64(time.Second)))
+	meter.lock.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (meter *meter) reader(ctx context.Context, reader io.Reader, sent *int64) io.Reader {
+	return &meterReader{ctx: ctx, reader: reader, meter: meter, sent: sent}
+}
+
+// meterReader 读取时限速并统计进度
+type meterReader struct {
+	ctx    context.Context
+	reader io.Reader
+	meter  *meter
+	sent   *int64
+}
+
+func (reader *meterReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	// 限速时按小块读取, 避免突发
+	if reader.meter.limit > 0 && len(p) > 32*1024 {
+		p = p[:32*1024]
+	}
+	n, err := reader.reader.Read(p)
+	if n > 0 {
+		if waitErr := reader.meter.wait(reader.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+		if reader.sent != nil {
+			atomic.AddInt64(reader.sent, int64(n))
+		}
+		reader.meter.add(int64(n))
+	}
+	return n, err
+}
+
+// PrintProgress 输出上传进度; 过程中的进度为 Debug 日志 (每秒最多一次), 上传完成时输出一条 Info 日志
+func PrintProgress(name string) func(uploaded, total int64) {
+	var lock sync.Mutex
+	var last time.Time
+	var finished bool
+	return func(uploaded, total int64) {
+		lock.Lock()
+		defer lock.Unlock()
+		if total > 0 && uploaded >= total {
+			// 分片重试时进度会回退, 完成日志只输出一次
+			if !finished {
+				finished = true
+				logger.Info("[Cloud Storage] Upload: %s 100%% (%s)", name, SizeFormat(total))
+			}
+			return
+		}
+		if finished || time.Since(last) < time.Second {
+			return
+		}
+		last = time.Now()
+		if total > 0 {
+			logger.Debug("[Cloud Storage] Upload: %s %.1f%% (%s / %s)", name,
+				float64(uploaded)*100/float64(total), SizeFormat(uploaded), SizeFormat(total))
+			return
+		}
+		logger.Debug("[Cloud Storage] Upload: %s (%s)", name, SizeFormat(uploaded))
+	}
+}
+
+// ParseSize 解析大小; 如 512K, 10M, 1G (1024 进制), 纯数字为字节
+func ParseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+	var unit int64 = 1
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			unit = 1 << 10
+		case 'M':
+			unit = 1 << 20
+		case 'G':
+			unit = 1 << 30
+		}
+		if unit > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("size error: %s", value)
+	}
+	return int64(number * float64(unit)), nil
+}
+
+// SizeFormat 文件大小格式化 (1024 进制)
+func SizeFormat(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
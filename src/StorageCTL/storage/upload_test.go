@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// fakeCOS 模拟 COS 分片上传接口; 只接受 uploadId 为 upload-N 的上传
+type fakeCOS struct {
+	lock      sync.Mutex
+	initiated int
+	aborted   int
+	parts     map[string][]byte
+	objects   map[string][]byte
+	completed string
+}
+
+func newFakeCOS(test *testing.T) (*fakeCOS, CloudStorage) {
+	var fake = &fakeCOS{parts: map[string][]byte{}, objects: map[string][]byte{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.lock.Lock()
+		defer fake.lock.Unlock()
+		var query = r.URL.Query()
+		var uploadId = query.Get("uploadId")
+		var active = uploadId != "" && uploadId == fmt.Sprintf("upload-%d", fake.initiated)
+		switch {
+		case r.Method == "POST" && query.Has("uploads"):
+			fake.initiated++
+			fake.parts = map[string][]byte{}
+			_, _ = fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>upload-%d</UploadId></InitiateMultipartUploadResult>`, fake.initiated)
+		case uploadId != "" && !active:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `<Error><Code>NoSuchUpload</Code><Message>upload not found</Message></Error>`)
+		case r.Method == "PUT" && uploadId != "":
+			data, _ := io.ReadAll(r.Body)
+			fake.parts[query.Get("partNumber")] = data
+			w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+			w.Header().Set("x-cos-hash-crc64ecma", strconv.FormatUint(crc64.Checksum(data, crc64.MakeTable(crc64.ECMA)), 10))
+		case r.Method == "GET" && uploadId != "":
+			var builder strings.Builder
+			builder.WriteString(`<ListPartsResult><UploadId>` + uploadId + `</UploadId><IsTruncated>false</IsTruncated>`)
+			for number, data := range fake.parts {
+				_, _ = fmt.Fprintf(&builder, `<Part><PartNumber>%s</PartNumber><ETag>"etag-%s"</ETag><Size>%d</Size></Part>`, number, number, len(data))
+			}
+			builder.WriteString(`</ListPartsResult>`)
+			_, _ = fmt.Fprint(w, builder.String())
+		case r.Method == "DELETE" && uploadId != "":
+			fake.aborted++
+			fake.parts = map[string][]byte{}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "POST" && uploadId != "":
+			data, _ := io.ReadAll(r.Body)
+			fake.completed = string(data)
+			_, _ = fmt.Fprint(w, `<CompleteMultipartUploadResult><Key>nacos/a.zip</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`)
+		case r.Method == "PUT":
+			data, _ := io.ReadAll(r.Body)
+			fake.objects[strings.TrimPrefix(r.URL.Path, "/")] = data
+			w.Header().Set("x-cos-hash-crc64ecma", strconv.FormatUint(crc64.Checksum(data, crc64.MakeTable(crc64.ECMA)), 10))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	test.Cleanup(server.Close)
+	cloudStorage, err := NewTencentCOS(server.URL, "id", "key")
+	if err != nil {
+		test.Fatal(err)
+	}
+	return fake, cloudStorage
+}
+
+// joined 按分片序号拼接的内容
+func (fake *fakeCOS) joined() []byte {
+	var joined []byte
+	for number := 1; number <= len(fake.parts); number++ {
+		joined = append(joined, fake.parts[fmt.Sprint(number)]...)
+	}
+	return joined
+}
+
+func TestUploadResume(test *testing.T) {
+	fake, cloudStorage := newFakeCOS(test)
+
+	var localPath = filepath.Join(test.TempDir(), "a.zip")
+	var content = bytes.Repeat([]byte("0123456789"), 1000)
+	err := os.WriteFile(localPath, content, 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	// 第一次上传两个分片后取消, 断点文件保留
+	ctx, cancel := context.WithCancel(context.Background())
+	var options = UploadOptions{PartSize: 1000, Concurrency: 1, Progress: func(uploaded, total int64) {
+		if uploaded >= 2000 {
+			cancel()
+		}
+	}}
+	if _, err = Upload(ctx, cloudStorage, localPath, "nacos/a.zip", options); err == nil {
+		test.Fatal("upload should be interrupted")
+	}
+	if _, err = os.Stat(localPath + ".upload"); err != nil {
+		test.Fatal(err)
+	}
+
+	// 续传剩余分片
+	var uploaded int64
+	options.Progress = func(value, total int64) {
+		uploaded = value
+	}
+	if _, err = Upload(context.Background(), cloudStorage, localPath, "nacos/a.zip", options); err != nil {
+		test.Fatal(err)
+	}
+	if fake.initiated != 1 || len(fake.parts) != 10 || uploaded != int64(len(content)) {
+		test.Fatal(fake.initiated, len(fake.parts), uploaded)
+	}
+	if !bytes.Equal(fake.joined(), content) || !strings.Contains(fake.completed, "<PartNumber>10</PartNumber>") {
+		test.Fatal(fake.completed)
+	}
+	if _, err = os.Stat(localPath + ".upload"); !os.IsNotExist(err) {
+		test.Fatal("checkpoint should be removed", err)
+	}
+}
+
+func TestUploadExpiredCheckpoint(test *testing.T) {
+	fake, cloudStorage := newFakeCOS(test)
+	var localPath = filepath.Join(test.TempDir(), "a.zip")
+	var content = bytes.Repeat([]byte("0123456789"), 500)
+	err := os.WriteFile(localPath, content, 0644)
+	if err != nil {
+		test.Fatal(err)
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	// 断点中的上传已过期 (服务端返回 NoSuchUpload), 重新开始上传
+	var state = checkpoint{CloudPath: "nacos/a.zip", UploadId: "expired", Size: info.Size(), ModTime: info.ModTime(), PartSize: 1000,
+		Parts: []Part{{Number: 1, ETag: `"etag-1"`, Size: 1000}}}
+	if err = state.save(localPath + ".upload"); err != nil {
+		test.Fatal(err)
+	}
+	if _, err = Upload(context.Background(), cloudStorage, localPath, "nacos/a.zip", UploadOptions{PartSize: 1000, Concurrency: 2}); err != nil {
+		test.Fatal(err)
+	}
+	if fake.initiated != 1 || !bytes.Equal(fake.joined(), content) {
+		test.Fatal(fake.initiated, len(fake.parts))
+	}
+}
+
+func TestUploadReader(test *testing.T) {
+	fake, cloudStorage := newFakeCOS(test)
+	var content = bytes.Repeat([]byte("0123456789"), 1050)
+	var uploaded int64
+	var options = UploadOptions{PartSize: 1000, Concurrency: 3, Progress: func(value, total int64) {
+		atomic.StoreInt64(&uploaded, value)
+	}}
+	// 流按分片并发上传, 最后一个分片不足分片大小
+	if _, err := UploadReader(context.Background(), cloudStorage, bytes.NewReader(content), "gitlab/a.tar", options); err != nil {
+		test.Fatal(err)
+	}
+	if fake.initiated != 1 || len(fake.parts) != 11 || !bytes.Equal(fake.joined(), content) || uploaded != int64(len(content)) {
+		test.Fatal(fake.initiated, len(fake.parts), uploaded)
+	}
+
+	// 小于分片大小时直接上传
+	if _, err := UploadReader(context.Background(), cloudStorage, strings.NewReader("small"), "gitlab/b.tar", options); err != nil {
+		test.Fatal(err)
+	}
+	if fake.initiated != 1 || string(fake.objects["gitlab/b.tar"]) != "small" {
+		test.Fatal(fake.initiated, fake.objects)
+	}
+
+	// 读取失败时取消分片上传
+	var reader = io.MultiReader(bytes.NewReader(content[:2500]), iotest.ErrReader(errors.New("read failed")))
+	if _, err := UploadReader(context.Background(), cloudStorage, reader, "gitlab/c.tar", options); err == nil || !strings.Contains(err.Error(), "read failed") {
+		test.Fatal(err)
+	}
+	if fake.aborted != 1 {
+		test.Fatal("upload should be aborted")
+	}
+}
+
+func TestUploadBandwidthLimit(test *testing.T) {
+	var root = test.TempDir()
+	cloudStorage, err := NewLocal(filepath.Join(root, "storage"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	var localPath = filepath.Join(root, "a.zip")
+	if err = os.WriteFile(localPath, make([]byte, 128*1024), 0644); err != nil {
+		test.Fatal(err)
+	}
+	var start = time.Now()
+	if _, err = Upload(context.Background(), cloudStorage, localPath, "nacos/a.zip", UploadOptions{BandwidthLimit: 512 * 1024}); err != nil {
+		test.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		test.Fatal("bandwidth limit not applied", elapsed)
+	}
+	if object, err := cloudStorage.Stat("nacos/a.zip"); err != nil || object.Size != 128*1024 {
+		test.Fatal(object, err)
+	}
+}
+
+func TestParseSize(test *testing.T) {
+	for value, expected := range map[string]int64{"": 0, "100": 100, "512K": 512 << 10, "10M": 10 << 20, "1.5GiB": 3 << 29, "2mb": 2 << 20} {
+		size, err := ParseSize(value)
+		if err != nil || size != expected {
+			test.Fatal(value, size, err)
+		}
+	}
+	if _, err := ParseSize("10X"); err == nil {
+		test.Fatal("size error expected")
+	}
+}
+
+func TestFilePartSize(test *testing.T) {
+	if size := filePartSize(100<<20, defaultPartSize); size != defaultPartSize {
+		test.Fatal(size)
+	}
+	// 分片数量不超过 maxParts
+	for _, size := range []int64{maxParts*1000 + 1, 200 << 30} {
+		var partSize = filePartSize(size, 1000)
+		if count := (size + partSize - 1) / partSize; partSize < 1000 || count > maxParts {
+			test.Fatal(size, partSize, count)
+		}
+	}
+}